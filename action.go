@@ -0,0 +1,60 @@
+package restful
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Action is a named operation on a resource that does not map onto one of
+// the CRUD interfaces. Create actions with ItemAction or CollectionAction and
+// return them from Actioner.Actions:
+//
+//	func (r *OrderResource) Actions() []restful.Action {
+//	    return []restful.Action{
+//	        restful.ItemAction(http.MethodPost, "cancel", r.cancel),       // POST /orders/:id/cancel
+//	        restful.CollectionAction(http.MethodGet, "search", r.search), // GET  /orders/search
+//	    }
+//	}
+type Action struct {
+	Name   string
+	Method string
+
+	item       func(id string, c *gin.Context) (any, int, error)
+	collection func(c *gin.Context) (any, int, error)
+}
+
+// ItemAction creates an action routed at /path/:id/<name>.
+func ItemAction(method, name string, handler func(id string, c *gin.Context) (any, int, error)) Action {
+	return Action{Name: name, Method: method, item: handler}
+}
+
+// CollectionAction creates an action routed at /path/<name>.
+func CollectionAction(method, name string, handler func(c *gin.Context) (any, int, error)) Action {
+	return Action{Name: name, Method: method, collection: handler}
+}
+
+func (a Action) handler() func(c *gin.Context) (any, int, error) {
+	if a.item != nil {
+		return func(c *gin.Context) (any, int, error) {
+			return a.item(c.Param("id"), c)
+		}
+	}
+	return a.collection
+}
+
+func (a Action) validate(path string) {
+	if a.Name == "" || strings.ContainsAny(a.Name, "/:*") {
+		panic(fmt.Sprintf("gin-restful: resource at %q declares an action with invalid name %q", path, a.Name))
+	}
+	if a.item == nil && a.collection == nil {
+		panic(fmt.Sprintf("gin-restful: action %q of resource at %q has no handler", a.Name, path))
+	}
+	switch a.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		panic(fmt.Sprintf("gin-restful: action %q of resource at %q has unsupported method %q", a.Name, path, a.Method))
+	}
+}
@@ -0,0 +1,151 @@
+package restful
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type orderResource struct{}
+
+func (r *orderResource) Get(id string, c *gin.Context) (any, int, error) {
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+func (r *orderResource) Actions() []Action {
+	return []Action{
+		ItemAction(http.MethodPost, "cancel", func(id string, c *gin.Context) (any, int, error) {
+			if id == "shipped" {
+				return nil, 0, Abort(http.StatusConflict, "order already shipped")
+			}
+			return gin.H{"id": id, "status": "cancelled"}, http.StatusOK, nil
+		}),
+		CollectionAction(http.MethodGet, "search", func(c *gin.Context) (any, int, error) {
+			return []string{c.Query("q")}, http.StatusOK, nil
+		}),
+	}
+}
+
+type actionOnlyResource struct{}
+
+func (r *actionOnlyResource) Actions() []Action {
+	return []Action{
+		CollectionAction(http.MethodPost, "reset", func(c *gin.Context) (any, int, error) {
+			return nil, http.StatusNoContent, nil
+		}),
+	}
+}
+
+type badActionResource struct{}
+
+func (r *badActionResource) Actions() []Action {
+	return []Action{ItemAction("CONNECT", "tunnel", nil)}
+}
+
+// --- tests ---
+
+func TestActions_ItemAction(t *testing.T) {
+	engine := setupRouter("/orders", &orderResource{})
+
+	w := doRequest(engine, "POST", "/api/orders/42/cancel", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"status":"cancelled"`) {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestActions_ItemAction_UsesErrorHandling(t *testing.T) {
+	engine := setupRouter("/orders", &orderResource{})
+
+	w := doRequest(engine, "POST", "/api/orders/shipped/cancel", "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "order already shipped") {
+		t.Errorf("expected HTTPError body, got %s", w.Body.String())
+	}
+}
+
+func TestActions_CollectionAction(t *testing.T) {
+	engine := setupRouter("/orders", &orderResource{})
+
+	w := doRequest(engine, "GET", "/api/orders/search?q=pizza", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Body.String() != `["pizza"]` {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestActions_ActionOnlyResource_DoesNotPanic(t *testing.T) {
+	engine := setupRouter("/cache", &actionOnlyResource{})
+
+	w := doRequest(engine, "POST", "/api/cache/reset", "")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+}
+
+func TestActions_InvalidAction_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for invalid action")
+		}
+	}()
+	setupRouter("/orders", &badActionResource{})
+}
+
+func TestActions_AllowHeader(t *testing.T) {
+	engine := gin.New()
+	engine.HandleMethodNotAllowed = true
+	api := NewAPI(engine, "/api")
+	api.AddResource("/orders", &orderResource{})
+
+	w := doRequest(engine, "GET", "/api/orders/42/cancel", "")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != http.MethodPost {
+		t.Errorf("expected Allow: POST, got %q", allow)
+	}
+}
+
+func TestAPI_Routes(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/orders", &orderResource{})
+
+	want := []RouteInfo{
+		{Method: http.MethodGet, Path: "/api/orders/:id", Resource: "orders", Name: "Get"},
+		{Method: http.MethodPost, Path: "/api/orders/:id/cancel", Resource: "orders", Name: "cancel"},
+		{Method: http.MethodGet, Path: "/api/orders/search", Resource: "orders", Name: "search"},
+	}
+	got := api.Routes()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d routes, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("route %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestHAL_ActionLinks(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithHAL())
+	api.AddResource("/orders", &orderResource{})
+
+	w := doRequest(engine, "GET", "/api/orders/42", "")
+	doc := decodeHAL(t, w.Body.Bytes())
+	if halHref(doc, "cancel") != "/api/orders/42/cancel" {
+		t.Errorf("expected cancel link, got %v", doc["_links"])
+	}
+}
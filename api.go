@@ -2,6 +2,9 @@ package restful
 
 import (
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,11 +25,113 @@ func WithErrorHandler(handler ErrorHandlerFunc) APIOption {
 	}
 }
 
+// ResourceOption configures optional settings for a single resource.
+type ResourceOption func(*resourceConfig)
+
+// resourceConfig holds the settings applied by ResourceOption.
+type resourceConfig struct {
+	bulkMaxItems     int
+	bulkAtomic       bool
+	idempotency      *idempotencyConfig
+	rateLimit        *rateLimitConfig
+	timeout          *timeoutConfig
+	concurrency      *concurrencyConfig
+	coalesce         *coalesceConfig
+	cache            *cacheConfig
+	cachePolicy      *CachePolicy
+	middleware       []gin.HandlerFunc
+	methodMiddleware map[string][]gin.HandlerFunc
+}
+
+// WithMiddleware attaches middleware to every route of the resource. It runs
+// after the API-level middleware added with Use.
+func WithMiddleware(middleware ...gin.HandlerFunc) ResourceOption {
+	return func(cfg *resourceConfig) {
+		cfg.middleware = append(cfg.middleware, middleware...)
+	}
+}
+
+// WithMethodMiddleware attaches middleware to the resource's routes for one
+// HTTP method, e.g. an admin check on http.MethodDelete. It applies to every
+// route of the resource with that method, including bulk and custom action
+// routes, and runs after the middleware added with WithMiddleware.
+func WithMethodMiddleware(method string, middleware ...gin.HandlerFunc) ResourceOption {
+	return func(cfg *resourceConfig) {
+		if cfg.methodMiddleware == nil {
+			cfg.methodMiddleware = make(map[string][]gin.HandlerFunc)
+		}
+		cfg.methodMiddleware[method] = append(cfg.methodMiddleware[method], middleware...)
+	}
+}
+
 // API manages RESTful resource registration under a common URL prefix.
 type API struct {
-	prefix       string
-	router       gin.IRouter
-	errorHandler ErrorHandlerFunc
+	prefix         string
+	router         gin.IRouter
+	errorHandler   ErrorHandlerFunc
+	authorizer     Authorizer
+	rateLimit      *rateLimitConfig
+	timeout        *timeoutConfig
+	recovery       bool
+	errorMappers   []ErrorMapper
+	errorObservers []ErrorObserver
+	correlationID  func(c *gin.Context) string
+	requestID      func() string
+	observers      []Observer
+	beforeHooks    []BeforeHookFunc
+	afterHooks     []AfterHookFunc
+	jsonapi        bool
+	hal            bool
+	middleware     []gin.HandlerFunc
+	resources      []*resourceEntry
+	handlers       []routeHandler
+}
+
+// routeHandler is a gin handler registered by the API, kept so that batch
+// sub-requests can be dispatched to the same handlers. path and handlers
+// include the base path and middleware of the router the API was created
+// with, as gin combines them when the route is registered.
+type routeHandler struct {
+	method   string
+	path     string
+	handlers []gin.HandlerFunc
+}
+
+// routerScope returns the base path of router and the middleware it runs
+// before its routes' handlers, and false if router is neither a
+// *gin.Engine nor a *gin.RouterGroup, whose middleware cannot be seen.
+func routerScope(router gin.IRouter) (string, []gin.HandlerFunc, bool) {
+	switch r := router.(type) {
+	case *gin.Engine:
+		return r.BasePath(), r.Handlers, true
+	case *gin.RouterGroup:
+		return r.BasePath(), r.Handlers, true
+	}
+	return "", nil, false
+}
+
+// resourceEntry records a resource registered through AddResource or
+// AddSingleton so that other resources' representations can link to it.
+type resourceEntry struct {
+	name      string
+	path      string
+	resource  any
+	singleton bool
+	config    resourceConfig
+	limiter   *concurrencyLimiter
+	coalescer *coalescer
+	cache     *responseCache
+	routes    []RouteInfo
+}
+
+// RouteInfo describes a route registered through AddResource or AddSingleton.
+type RouteInfo struct {
+	Method   string
+	Path     string
+	Resource string
+	// Name is the handler method ("List", "Get", "Post", "Put", "Patch",
+	// "Delete") or the name of a custom action.
+	Name string
 }
 
 // NewAPI creates a new API with the given router and URL prefix.
@@ -37,66 +142,395 @@ func NewAPI(router gin.IRouter, prefix string, opts ...APIOption) *API {
 	for _, opt := range opts {
 		opt(api)
 	}
+	if api.jsonapi && api.errorHandler == nil {
+		api.errorHandler = handleJSONAPIError
+	}
+	if api.requestID != nil && api.correlationID == nil {
+		api.correlationID = RequestID
+	}
+	api.errorHandler = api.decorateErrorHandler(api.errorHandler)
 	return api
 }
 
 // AddResource registers a resource at the given path. The resource is inspected
 // via type assertions to determine which HTTP method interfaces it implements.
-// Only implemented interfaces become routes. Panics if the resource implements
-// none of the handler interfaces (Lister, Getter, Poster, Putter, Patcher, Deleter).
-func (api *API) AddResource(path string, resource any) {
+// Only implemented interfaces become routes, followed by any custom actions
+// declared through Actioner. BulkPoster, BulkPatcher and BulkDeleter map to
+// POST, PATCH and DELETE on the collection path. Panics if the resource
+// implements none of the handler interfaces and declares no actions.
+func (api *API) AddResource(path string, resource any, opts ...ResourceOption) {
 	fullPath := normalizePath(api.prefix + path)
-	registered := 0
+	entry := newResourceEntry(fullPath, resource, opts)
 
-	makeH := func(fn func(c *gin.Context) (any, int, error)) gin.HandlerFunc {
-		return makeHandlerWithErrorHandler(fn, api.errorHandler)
+	var bulkPost func(c *gin.Context) (any, int, error)
+	if r, ok := resource.(BulkPoster); ok {
+		bulkPost = bulkHandler(entry.config, bulkTransactor(entry, path), decodeRawItems, r.BulkPost)
 	}
 
 	if r, ok := resource.(Poster); ok {
-		registered++
-		api.router.POST(fullPath, makeH(func(c *gin.Context) (any, int, error) {
+		post := api.wrap(entry, http.MethodPost, fullPath, "Post", true, func(c *gin.Context) (any, int, error) {
 			return r.Post(c)
-		}))
+		})
+		if bulkPost != nil {
+			bulk := api.wrap(entry, http.MethodPost, fullPath, "BulkPost", false, bulkPost)
+			pick := func(c *gin.Context) string {
+				if isJSONArrayBody(c) {
+					return "BulkPost"
+				}
+				return "Post"
+			}
+			api.register(entry, http.MethodPost, fullPath, pick, func(c *gin.Context) {
+				if c.GetString(routeOperationKey) == "BulkPost" {
+					bulk(c)
+					return
+				}
+				post(c)
+			}, "Post", "BulkPost")
+		} else {
+			api.register(entry, http.MethodPost, fullPath, nil, post, "Post")
+		}
+	} else if bulkPost != nil {
+		api.handle(entry, http.MethodPost, fullPath, "BulkPost", false, bulkPost)
 	}
 
-	if r, ok := resource.(Lister); ok {
-		registered++
-		api.router.GET(fullPath, makeH(func(c *gin.Context) (any, int, error) {
-			return r.List(c)
+	if r, ok := resource.(StreamLister); ok {
+		// Streamed items are written as plain JSON, whatever the API's
+		// representation format.
+		api.handle(entry, http.MethodGet, fullPath, "List", false, filterRows(entry, func(c *gin.Context) (any, int, error) {
+			stream, err := r.StreamList(c)
+			if err != nil {
+				return nil, 0, err
+			}
+			return stream, http.StatusOK, nil
 		}))
+	} else if r, ok := resource.(Lister); ok {
+		api.handle(entry, http.MethodGet, fullPath, "List", true, filterRows(entry, coalesce(entry, func(c *gin.Context) (any, int, error) {
+			return r.List(c)
+		})))
+	}
+
+	if r, ok := resource.(BulkPatcher); ok {
+		api.handle(entry, http.MethodPatch, fullPath, "BulkPatch", false, bulkHandler(entry.config, bulkTransactor(entry, path), decodeRawItems, r.BulkPatch))
+	}
+
+	if r, ok := resource.(BulkDeleter); ok {
+		api.handle(entry, http.MethodDelete, fullPath, "BulkDelete", false, bulkHandler(entry.config, bulkTransactor(entry, path), decodeBulkIDs, r.BulkDelete))
 	}
 
 	idPath := fullPath + "/:id"
 
 	if r, ok := resource.(Getter); ok {
-		registered++
-		api.router.GET(idPath, makeH(func(c *gin.Context) (any, int, error) {
+		api.handle(entry, http.MethodGet, idPath, "Get", true, coalesce(entry, func(c *gin.Context) (any, int, error) {
 			return r.Get(c.Param("id"), c)
 		}))
 	}
 
 	if r, ok := resource.(Putter); ok {
-		registered++
-		api.router.PUT(idPath, makeH(func(c *gin.Context) (any, int, error) {
+		api.handle(entry, http.MethodPut, idPath, "Put", true, func(c *gin.Context) (any, int, error) {
 			return r.Put(c.Param("id"), c)
-		}))
+		})
 	}
 
 	if r, ok := resource.(Patcher); ok {
-		registered++
-		api.router.PATCH(idPath, makeH(func(c *gin.Context) (any, int, error) {
+		api.handle(entry, http.MethodPatch, idPath, "Patch", true, func(c *gin.Context) (any, int, error) {
 			return r.Patch(c.Param("id"), c)
-		}))
+		})
 	}
 
 	if r, ok := resource.(Deleter); ok {
-		registered++
-		api.router.DELETE(idPath, makeH(func(c *gin.Context) (any, int, error) {
+		api.handle(entry, http.MethodDelete, idPath, "Delete", true, func(c *gin.Context) (any, int, error) {
 			return r.Delete(c.Param("id"), c)
-		}))
+		})
 	}
 
-	if registered == 0 {
+	api.addActions(entry, path, idPath)
+
+	if len(entry.routes) == 0 {
 		panic(fmt.Sprintf("gin-restful: resource at %q implements none of the handler interfaces", path))
 	}
+	api.resources = append(api.resources, entry)
+}
+
+// AddSingleton registers a resource that is a single object rather than a
+// collection, such as "/me", "/settings" or "/users/:id/profile". Its
+// handlers are routed at the path itself with no id segment:
+// SingletonGetter, SingletonPutter, SingletonPatcher and SingletonDeleter
+// map to GET, PUT, PATCH and DELETE on the path. Parent ids of nested
+// singletons are available through c.Param. Collection actions declared
+// through Actioner are routed at /path/<name>. Panics if the resource
+// implements none of the singleton interfaces and declares no actions.
+func (api *API) AddSingleton(path string, resource any, opts ...ResourceOption) {
+	fullPath := normalizePath(api.prefix + path)
+	entry := newResourceEntry(fullPath, resource, opts)
+	entry.singleton = true
+
+	if r, ok := resource.(SingletonGetter); ok {
+		api.handle(entry, http.MethodGet, fullPath, "Get", true, coalesce(entry, func(c *gin.Context) (any, int, error) {
+			return r.Get(c)
+		}))
+	}
+
+	if r, ok := resource.(SingletonPutter); ok {
+		api.handle(entry, http.MethodPut, fullPath, "Put", true, func(c *gin.Context) (any, int, error) {
+			return r.Put(c)
+		})
+	}
+
+	if r, ok := resource.(SingletonPatcher); ok {
+		api.handle(entry, http.MethodPatch, fullPath, "Patch", true, func(c *gin.Context) (any, int, error) {
+			return r.Patch(c)
+		})
+	}
+
+	if r, ok := resource.(SingletonDeleter); ok {
+		api.handle(entry, http.MethodDelete, fullPath, "Delete", true, func(c *gin.Context) (any, int, error) {
+			return r.Delete(c)
+		})
+	}
+
+	api.addActions(entry, path, "")
+
+	if len(entry.routes) == 0 {
+		panic(fmt.Sprintf("gin-restful: singleton at %q implements none of the singleton interfaces", path))
+	}
+	api.resources = append(api.resources, entry)
+}
+
+func newResourceEntry(fullPath string, resource any, opts []ResourceOption) *resourceEntry {
+	entry := &resourceEntry{name: resourceName(fullPath), path: fullPath, resource: resource}
+	for _, opt := range opts {
+		opt(&entry.config)
+	}
+	return entry
+}
+
+// bulkTransactor returns the transaction hook for entry's bulk operations,
+// or nil when they are not atomic.
+func bulkTransactor(entry *resourceEntry, path string) BulkTransactor {
+	if !entry.config.bulkAtomic {
+		return nil
+	}
+	txer, ok := entry.resource.(BulkTransactor)
+	if !ok {
+		panic(fmt.Sprintf("gin-restful: resource at %q uses WithBulkAtomic but does not implement BulkTransactor", path))
+	}
+	return txer
+}
+
+// addActions registers the custom actions of entry's resource. Item actions
+// are routed below idPath; singletons pass an empty idPath and may only
+// declare collection actions.
+func (api *API) addActions(entry *resourceEntry, path, idPath string) {
+	r, ok := entry.resource.(Actioner)
+	if !ok {
+		return
+	}
+	// Action results are rendered as plain JSON: they are usually status
+	// objects rather than representations of the resource.
+	for _, action := range r.Actions() {
+		action.validate(path)
+		routePath := entry.path + "/" + action.Name
+		if action.item != nil {
+			if idPath == "" {
+				panic(fmt.Sprintf("gin-restful: singleton at %q declares item action %q", path, action.Name))
+			}
+			routePath = idPath + "/" + action.Name
+		}
+		api.handle(entry, action.Method, routePath, action.Name, false, action.handler())
+	}
+}
+
+// represent applies the API's representation format to fn.
+func (api *API) represent(entry *resourceEntry, fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	if api.jsonapi {
+		return jsonapiHandler(fn, entry)
+	}
+	if api.hal {
+		return api.halHandler(fn, entry)
+	}
+	return fn
+}
+
+// handle registers fn as the handler of the named operation. Results of
+// represented operations are rendered in the API's representation format.
+func (api *API) handle(entry *resourceEntry, method, routePath, name string, represent bool, fn func(c *gin.Context) (any, int, error)) {
+	api.register(entry, method, routePath, nil, api.wrap(entry, method, routePath, name, represent, fn), name)
+}
+
+// wrap turns fn into a gin handler for the named operation. The handler
+// makes the Operation available through GetOperation, enforces the
+// resource's Policy, runs the lifecycle hooks around fn and renders the
+// result or error.
+func (api *API) wrap(entry *resourceEntry, method, routePath, name string, represent bool, fn func(c *gin.Context) (any, int, error)) gin.HandlerFunc {
+	operation := operationOf(entry, method, routePath, name)
+
+	fn = api.withHooks(entry, fn)
+	fn = api.withPolicy(entry, name, fn)
+	if represent {
+		fn = api.represent(entry, fn)
+	}
+	fn = withCachePolicy(entry, name, fn)
+	if api.recovery {
+		fn = recoverPanics(fn)
+	}
+	h := makeHandlerWithErrorHandler(fn, api.errorHandler)
+	timeout := entry.config.timeout
+	if timeout == nil {
+		timeout = api.timeout
+	}
+	if timeout != nil {
+		if d := timeout.duration(name); d > 0 {
+			h = timeout.withTimeout(d, api.errorHandler, h)
+		}
+	}
+	if coalesces(entry, name) {
+		h = withCoalesceScope(h)
+	}
+
+	return func(c *gin.Context) {
+		c.Set(operationKey, operation(c))
+		h(c)
+	}
+}
+
+// operationOf returns a function resolving the Operation of requests to the
+// named operation of entry served at routePath.
+func operationOf(entry *resourceEntry, method, routePath, name string) func(c *gin.Context) Operation {
+	op := Operation{Resource: entry.name, Name: name, Method: method, Route: routePath}
+	hasID := !entry.singleton && strings.HasPrefix(routePath, entry.path+"/:id")
+	return func(c *gin.Context) Operation {
+		current := op
+		if hasID {
+			current.ID = c.Param("id")
+		}
+		return current
+	}
+}
+
+// register adds h to the router behind the API, resource and method
+// middleware, the rate limits, the concurrency limit and idempotency
+// handling, and records the route on entry under each of names. When the
+// route serves several operations, pick chooses the one serving each
+// request before anything else runs; see routeOperation.
+func (api *API) register(entry *resourceEntry, method, routePath string, pick func(c *gin.Context) string, h gin.HandlerFunc, names ...string) {
+	var handlers []gin.HandlerFunc
+	if pick != nil {
+		handlers = append(handlers, func(c *gin.Context) {
+			c.Set(routeOperationKey, pick(c))
+			c.Next()
+		})
+	}
+	if api.requestID != nil {
+		handlers = append(handlers, api.assignRequestID)
+	}
+	if len(api.observers) > 0 {
+		handlers = append(handlers, api.observe(operationOf(entry, method, routePath, names[0])))
+	}
+	handlers = append(handlers, api.middlewareChain()...)
+	if api.rateLimit != nil {
+		handlers = append(handlers, api.rateLimit.handler("*", names))
+	}
+	handlers = append(handlers, entry.config.middleware...)
+	handlers = append(handlers, entry.config.methodMiddleware[method]...)
+	if entry.config.rateLimit != nil {
+		handlers = append(handlers, entry.config.rateLimit.handler(entry.path, names))
+	}
+	if cfg := entry.config.cache; cfg != nil {
+		if entry.cache == nil {
+			_, filtered := entry.resource.(RowFilter)
+			entry.cache = newResponseCache(cfg, entry.path, filtered)
+		}
+		item := !entry.singleton && (routePath == entry.path+"/:id" || strings.HasPrefix(routePath, entry.path+"/:id/"))
+		var authorize func(c *gin.Context) error
+		if check := api.authorization(entry, names[0]); check != nil {
+			operation := operationOf(entry, method, routePath, names[0])
+			authorize = func(c *gin.Context) error {
+				c.Set(operationKey, operation(c))
+				return check(c)
+			}
+		}
+		if h := entry.cache.handler(method, names, item, authorize); h != nil {
+			handlers = append(handlers, h)
+		}
+	}
+	if cfg := entry.config.concurrency; cfg != nil && slices.ContainsFunc(names, cfg.applies) {
+		if entry.limiter == nil {
+			entry.limiter = newConcurrencyLimiter(cfg, entry.name, api.observers)
+		}
+		limit := entry.limiter.handler
+		if len(names) > 1 {
+			limit = func(c *gin.Context) {
+				if cfg.applies(routeOperation(c, names)) {
+					entry.limiter.handler(c)
+				}
+			}
+		}
+		handlers = append(handlers, limit)
+	}
+	if cfg := entry.config.idempotency; cfg != nil && slices.ContainsFunc(names, func(name string) bool {
+		return slices.Contains(idempotentOperations, name)
+	}) {
+		handlers = append(handlers, cfg.handler)
+	}
+	handlers = append(handlers, h)
+
+	api.router.Handle(method, routePath, handlers...)
+	base, middleware, _ := routerScope(api.router)
+	api.handlers = append(api.handlers, routeHandler{
+		method:   method,
+		path:     normalizePath(base + "/" + routePath),
+		handlers: append(slices.Clone(middleware), handlers...),
+	})
+	for _, name := range names {
+		entry.routes = append(entry.routes, RouteInfo{
+			Method:   method,
+			Path:     routePath,
+			Resource: entry.name,
+			Name:     name,
+		})
+	}
+}
+
+// routeOperationKey holds the operation picked for a request to a route
+// serving several operations.
+const routeOperationKey = "gin-restful.route-operation"
+
+// routeOperation returns which of names, the operations of the route, serves
+// the request.
+func routeOperation(c *gin.Context, names []string) string {
+	if len(names) > 1 {
+		if name := c.GetString(routeOperationKey); name != "" {
+			return name
+		}
+	}
+	return names[0]
+}
+
+// middlewareChain returns the handlers that run first on every route of the
+// API: the middleware added with Use, preceded by a handler that makes the
+// API's error handler available to RenderError.
+func (api *API) middlewareChain() []gin.HandlerFunc {
+	handlers := []gin.HandlerFunc{func(c *gin.Context) {
+		c.Set(errorHandlerKey, api.errorHandler)
+	}}
+	return append(handlers, api.middleware...)
+}
+
+// Use adds middleware to every route registered through this API afterwards,
+// including the batch endpoint. Unlike middleware on the underlying router
+// or group, it does not affect routes registered elsewhere on the engine.
+// As with gin's Use, routes registered before the call are not affected.
+func (api *API) Use(middleware ...gin.HandlerFunc) {
+	api.middleware = append(api.middleware, middleware...)
+}
+
+// Routes returns the routes registered through AddResource and AddSingleton,
+// in registration order. It is intended for generating documentation and
+// route listings.
+func (api *API) Routes() []RouteInfo {
+	var routes []RouteInfo
+	for _, entry := range api.resources {
+		routes = append(routes, entry.routes...)
+	}
+	return routes
 }
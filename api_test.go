@@ -92,6 +92,16 @@ func doRequest(engine *gin.Engine, method, path string, body string) *httptest.R
 	return w
 }
 
+func doRequestWithHeaders(engine *gin.Engine, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
 // --- tests: partial implementation ---
 
 func TestAddResource_ReadOnly_RegistersOnlyGetRoutes(t *testing.T) {
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+)
+
+// APIKeyStore looks up the principal an API key belongs to. LookupAPIKey
+// returns a nil principal and a nil error for unknown keys; errors are
+// reserved for failures of the store itself.
+type APIKeyStore interface {
+	LookupAPIKey(ctx context.Context, key string) (*restful.Principal, error)
+}
+
+// StaticKeys is an APIKeyStore holding plaintext keys, compared in constant
+// time. It suits development and a handful of service keys; prefer
+// HashedKeys when the keys are kept in configuration files.
+type StaticKeys map[string]*restful.Principal
+
+// LookupAPIKey implements APIKeyStore.
+func (s StaticKeys) LookupAPIKey(_ context.Context, key string) (*restful.Principal, error) {
+	var found *restful.Principal
+	for k, p := range s {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			found = p
+		}
+	}
+	return found, nil
+}
+
+// HashedKeys is an APIKeyStore indexed by the hex-encoded SHA-256 hash of
+// each key, as returned by HashAPIKey, so that the keys themselves need not
+// be stored.
+type HashedKeys map[string]*restful.Principal
+
+// LookupAPIKey implements APIKeyStore.
+func (s HashedKeys) LookupAPIKey(_ context.Context, key string) (*restful.Principal, error) {
+	return s[HashAPIKey(key)], nil
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 hash of key, the index of
+// HashedKeys. API keys are high-entropy random strings, so a fast hash is
+// sufficient, unlike for passwords.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyConfig configures the API key authenticator.
+type APIKeyConfig struct {
+	// Store resolves keys to principals. Required.
+	Store APIKeyStore
+	// Header carries the key. Defaults to "X-API-Key".
+	Header string
+	// Query, when set, is a query parameter also accepted to carry the key.
+	Query string
+	// Realm is sent in WWW-Authenticate challenges. Defaults to "restricted".
+	Realm string
+}
+
+// APIKey returns an Authenticator for API keys sent in a header or query
+// parameter. Panics if cfg.Store is nil.
+func APIKey(cfg APIKeyConfig) Authenticator {
+	if cfg.Store == nil {
+		panic("gin-restful/auth: APIKeyConfig.Store is required")
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Key"
+	}
+	cfg.Realm = realmOrDefault(cfg.Realm)
+	return &apiKeyAuthenticator{cfg: cfg}
+}
+
+type apiKeyAuthenticator struct {
+	cfg APIKeyConfig
+}
+
+func (a *apiKeyAuthenticator) Challenge() string {
+	return fmt.Sprintf("APIKey realm=%q, header=%q", a.cfg.Realm, a.cfg.Header)
+}
+
+func (a *apiKeyAuthenticator) Authenticate(c *gin.Context) (*restful.Principal, error) {
+	key := c.GetHeader(a.cfg.Header)
+	if key == "" && a.cfg.Query != "" {
+		key = c.Query(a.cfg.Query)
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	principal, err := a.cfg.Store.LookupAPIKey(c.Request.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, unauthorized("invalid API key",
+			fmt.Sprintf("APIKey realm=%q, header=%q, error=\"invalid_key\"", a.cfg.Realm, a.cfg.Header))
+	}
+	return principal, nil
+}
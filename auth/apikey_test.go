@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAPIKey_HashedStoreAndQuery(t *testing.T) {
+	store := HashedKeys{HashAPIKey("k-123"): {Subject: "reporting"}}
+	engine := setupAuthRouter(Middleware(APIKey(APIKeyConfig{Store: store, Header: "X-Key", Query: "api_key"})))
+
+	if w := doAuthRequest(engine, map[string]string{"X-Key": "k-123"}); w.Body.String() != `{"subject":"reporting"}` {
+		t.Errorf("expected header key to authenticate, got %d %s", w.Code, w.Body.String())
+	}
+
+	req := doAuthRequest(engine, map[string]string{"X-API-Key": "k-123"})
+	if req.Code != http.StatusUnauthorized {
+		t.Errorf("expected the default header to be ignored, got %d", req.Code)
+	}
+	if got := req.Header().Get("WWW-Authenticate"); got != `APIKey realm="restricted", header="X-Key"` {
+		t.Errorf("unexpected challenge: %q", got)
+	}
+}
+
+func TestAPIKey_InvalidKey(t *testing.T) {
+	engine := setupAuthRouter(Middleware(APIKey(APIKeyConfig{Store: testKeys})))
+
+	w := doAuthRequest(engine, map[string]string{"X-API-Key": "secret-key2"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != `APIKey realm="restricted", header="X-API-Key", error="invalid_key"` {
+		t.Errorf("unexpected challenge: %q", got)
+	}
+}
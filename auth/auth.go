@@ -0,0 +1,98 @@
+// Package auth provides authentication middleware for gin-restful APIs.
+//
+// Authenticators verify the credentials of a request — a JWT bearer token,
+// an API key or HTTP Basic credentials — and produce a restful.Principal.
+// Middleware stores it on the gin context with restful.SetPrincipal, where
+// resources and authorization policies read it with restful.PrincipalFrom:
+//
+//	keys, _ := auth.LoadJWKS("jwks.json")
+//	api := restful.NewAPI(engine, "/api/v1")
+//	api.Use(auth.Middleware(auth.JWT(auth.JWTConfig{Keys: keys, Issuer: "https://id.example.com"})))
+//
+// Failed authentication is reported as a 401 restful.HTTPError carrying a
+// WWW-Authenticate challenge, rendered through the API's error handler.
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries
+// none of the credentials it handles, as opposed to invalid ones.
+var ErrNoCredentials = errors.New("auth: no credentials")
+
+// Authenticator verifies the credentials of a request.
+type Authenticator interface {
+	// Authenticate returns the principal of the request. It returns
+	// ErrNoCredentials when the request has no credentials for this
+	// authenticator, and an error, usually a 401 *restful.HTTPError, when
+	// they are invalid.
+	Authenticate(c *gin.Context) (*restful.Principal, error)
+	// Challenge returns the WWW-Authenticate challenge sent when a request
+	// has no credentials, e.g. `Bearer realm="api"`.
+	Challenge() string
+}
+
+// Middleware authenticates every request with the first of authenticators
+// for which it carries credentials and stores the principal with
+// restful.SetPrincipal. Requests without any credentials get a 401 listing
+// the challenges of all authenticators.
+func Middleware(authenticators ...Authenticator) gin.HandlerFunc {
+	return middleware(authenticators, false)
+}
+
+// Optional is like Middleware but lets requests without credentials through
+// anonymously, leaving it to authorization policies to decide whether an
+// operation is public. Requests with invalid credentials are still rejected.
+func Optional(authenticators ...Authenticator) gin.HandlerFunc {
+	return middleware(authenticators, true)
+}
+
+func middleware(authenticators []Authenticator, optional bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, a := range authenticators {
+			principal, err := a.Authenticate(c)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				restful.RenderError(c, err)
+				return
+			}
+			restful.SetPrincipal(c, principal)
+			c.Next()
+			return
+		}
+		if optional {
+			c.Next()
+			return
+		}
+
+		opts := []restful.ErrorOption{restful.WithCode("UNAUTHORIZED")}
+		for _, a := range authenticators {
+			opts = append(opts, restful.WithHeader("WWW-Authenticate", a.Challenge()))
+		}
+		restful.RenderError(c, restful.Abort(http.StatusUnauthorized, "authentication required", opts...))
+	}
+}
+
+// unauthorized returns a 401 error for invalid credentials with the given
+// WWW-Authenticate challenge.
+func unauthorized(message, challenge string) error {
+	return restful.Abort(http.StatusUnauthorized, message,
+		restful.WithCode("UNAUTHORIZED"),
+		restful.WithHeader("WWW-Authenticate", challenge),
+	)
+}
+
+// realmOrDefault returns realm, or "restricted" when it is empty.
+func realmOrDefault(realm string) string {
+	if realm == "" {
+		return "restricted"
+	}
+	return realm
+}
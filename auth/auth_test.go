@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+)
+
+// --- helpers ---
+
+type meResource struct{}
+
+func (r *meResource) Get(c *gin.Context) (any, int, error) {
+	p, ok := restful.PrincipalFrom(c)
+	if !ok {
+		return gin.H{"subject": ""}, http.StatusOK, nil
+	}
+	return gin.H{"subject": p.Subject}, http.StatusOK, nil
+}
+
+func setupAuthRouter(mw gin.HandlerFunc, opts ...restful.APIOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	api := restful.NewAPI(engine, "/api", opts...)
+	api.Use(mw)
+	api.AddSingleton("/me", &meResource{})
+	return engine
+}
+
+func doAuthRequest(engine *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+var testKeys = StaticKeys{"secret-key": {Subject: "svc"}}
+
+// --- tests ---
+
+func TestMiddleware_NoCredentials_ListsChallenges(t *testing.T) {
+	engine := setupAuthRouter(Middleware(
+		APIKey(APIKeyConfig{Store: testKeys}),
+		Basic(BasicConfig{Store: BasicUsers{}, Realm: "api"}),
+	))
+
+	w := doAuthRequest(engine, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	challenges := w.Header().Values("WWW-Authenticate")
+	if len(challenges) != 2 || challenges[1] != `Basic realm="api", charset="UTF-8"` {
+		t.Errorf("unexpected challenges: %q", challenges)
+	}
+}
+
+func TestMiddleware_FirstAuthenticatorWithCredentialsWins(t *testing.T) {
+	engine := setupAuthRouter(Middleware(
+		Basic(BasicConfig{Store: BasicUsers{}}),
+		APIKey(APIKeyConfig{Store: testKeys}),
+	))
+
+	w := doAuthRequest(engine, map[string]string{"X-API-Key": "secret-key"})
+	if w.Code != http.StatusOK || w.Body.String() != `{"subject":"svc"}` {
+		t.Errorf("expected the API key to authenticate, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOptional_AllowsAnonymousButRejectsInvalid(t *testing.T) {
+	engine := setupAuthRouter(Optional(APIKey(APIKeyConfig{Store: testKeys})))
+
+	if w := doAuthRequest(engine, nil); w.Code != http.StatusOK {
+		t.Errorf("expected anonymous request to pass, got %d", w.Code)
+	}
+	if w := doAuthRequest(engine, map[string]string{"X-API-Key": "wrong"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected invalid key to be rejected, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_UsesAPIErrorHandler(t *testing.T) {
+	engine := setupAuthRouter(Middleware(APIKey(APIKeyConfig{Store: testKeys})), restful.WithJSONAPI())
+
+	w := doAuthRequest(engine, map[string]string{"X-API-Key": "wrong"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != restful.JSONAPIMediaType {
+		t.Errorf("expected JSON:API error document, got %q: %s", ct, w.Body.String())
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("expected WWW-Authenticate header")
+	}
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicUser is an account known to a BasicStore.
+type BasicUser struct {
+	// PasswordHash is a bcrypt hash ("$2a$...", "$2b$..." or "$2y$...") or
+	// an argon2id hash in PHC string format ("$argon2id$v=19$m=...,t=...,p=...$salt$hash"),
+	// such as those produced by HashPassword.
+	PasswordHash string
+	Principal    *restful.Principal
+}
+
+// BasicStore looks up the account for a username. LookupUser returns a nil
+// user and a nil error for unknown usernames; errors are reserved for
+// failures of the store itself.
+type BasicStore interface {
+	LookupUser(ctx context.Context, username string) (*BasicUser, error)
+}
+
+// BasicUsers is an in-memory BasicStore keyed by username.
+type BasicUsers map[string]*BasicUser
+
+// LookupUser implements BasicStore.
+func (s BasicUsers) LookupUser(_ context.Context, username string) (*BasicUser, error) {
+	return s[username], nil
+}
+
+// BasicConfig configures the HTTP Basic authenticator.
+type BasicConfig struct {
+	// Store resolves usernames to accounts. Required.
+	Store BasicStore
+	// Realm is sent in WWW-Authenticate challenges. Defaults to "restricted".
+	Realm string
+}
+
+// Basic returns an Authenticator for HTTP Basic credentials (RFC 7617)
+// checked against bcrypt or argon2id password hashes. Panics if cfg.Store
+// is nil.
+func Basic(cfg BasicConfig) Authenticator {
+	if cfg.Store == nil {
+		panic("gin-restful/auth: BasicConfig.Store is required")
+	}
+	cfg.Realm = realmOrDefault(cfg.Realm)
+	return &basicAuthenticator{cfg: cfg}
+}
+
+type basicAuthenticator struct {
+	cfg BasicConfig
+}
+
+func (a *basicAuthenticator) Challenge() string {
+	return fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", a.cfg.Realm)
+}
+
+func (a *basicAuthenticator) Authenticate(c *gin.Context) (*restful.Principal, error) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	user, err := a.cfg.Store.LookupUser(c.Request.Context(), username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// Hash the password anyway, so that response times do not tell
+		// unknown usernames apart from wrong passwords.
+		_, _ = CheckPassword(dummyPasswordHash(), password)
+		return nil, unauthorized("invalid username or password", a.Challenge())
+	}
+	match, err := CheckPassword(user.PasswordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, unauthorized("invalid username or password", a.Challenge())
+	}
+	return user.Principal, nil
+}
+
+// argon2id parameters used by HashPassword, following the RFC 9106
+// recommendation for memory-constrained environments.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// dummyPasswordHash returns a hash of a random password, checked against
+// the passwords given for unknown usernames.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := HashPassword(rand.Text())
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// HashPassword returns an argon2id hash of password in PHC string format,
+// suitable for BasicUser.PasswordHash.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword reports whether password matches hash, a bcrypt or argon2id
+// hash. It returns an error for hashes in neither format.
+func CheckPassword(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+
+	case strings.HasPrefix(hash, "$argon2id$"):
+		var (
+			version, memory, iterations int
+			threads                     uint8
+		)
+		parts := strings.Split(hash, "$")
+		if len(parts) != 6 {
+			return false, errors.New("auth: malformed argon2id hash")
+		}
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+			return false, errors.New("auth: unsupported argon2id version")
+		}
+		if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+			return false, errors.New("auth: malformed argon2id parameters")
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+		if err != nil {
+			return false, errors.New("auth: malformed argon2id salt")
+		}
+		want, err := base64.RawStdEncoding.DecodeString(parts[5])
+		if err != nil {
+			return false, errors.New("auth: malformed argon2id key")
+		}
+		got := argon2.IDKey([]byte(password), salt, uint32(iterations), uint32(memory), threads, uint32(len(want)))
+		return subtle.ConstantTimeCompare(got, want) == 1, nil
+	}
+	return false, errors.New("auth: unsupported password hash format")
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/hwangseonu/gin-restful"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func basicHeader(username, password string) map[string]string {
+	return map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))}
+}
+
+func TestBasic_BcryptAndArgon2(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := setupAuthRouter(Middleware(Basic(BasicConfig{Store: BasicUsers{
+		"alice": {PasswordHash: string(bcryptHash), Principal: &restful.Principal{Subject: "alice"}},
+		"bob":   {PasswordHash: argonHash, Principal: &restful.Principal{Subject: "bob"}},
+	}})))
+
+	tests := []struct {
+		username, password string
+		want               int
+	}{
+		{"alice", "hunter2", http.StatusOK},
+		{"alice", "hunter3", http.StatusUnauthorized},
+		{"bob", "correct horse", http.StatusOK},
+		{"bob", "wrong horse", http.StatusUnauthorized},
+		{"carol", "hunter2", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := doAuthRequest(engine, basicHeader(tt.username, tt.password))
+		if w.Code != tt.want {
+			t.Errorf("%s/%s: expected %d, got %d", tt.username, tt.password, tt.want, w.Code)
+		}
+		if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != `Basic realm="restricted", charset="UTF-8"` {
+			t.Errorf("%s: unexpected challenge %q", tt.username, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestCheckPassword_UnsupportedFormat(t *testing.T) {
+	if _, err := CheckPassword("md5:abc", "x"); err == nil {
+		t.Error("expected an error for an unsupported hash format")
+	}
+}
+
+func TestBasic_UnknownUserChecksDummyHash(t *testing.T) {
+	checked := false
+	defer func(orig func() string) { dummyPasswordHash = orig }(dummyPasswordHash)
+	dummyPasswordHash = func() string {
+		checked = true
+		return "$argon2id$v=19$m=8,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5a2V5a2V5"
+	}
+	engine := setupAuthRouter(Middleware(Basic(BasicConfig{Store: BasicUsers{}})))
+
+	if w := doAuthRequest(engine, basicHeader("carol", "hunter2")); w.Code != http.StatusUnauthorized || !checked {
+		t.Errorf("expected a 401 after checking the dummy hash, got %d (checked %v)", w.Code, checked)
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Key is a JWT verification key.
+type Key struct {
+	// ID matches the "kid" header of tokens signed with the key. Tokens
+	// without a kid are checked against every key usable for their
+	// algorithm.
+	ID string
+	// Algorithm restricts the key to one JWS algorithm, e.g. "RS256".
+	// Empty allows every algorithm of the key's type.
+	Algorithm string
+	// Key is a []byte HMAC secret, an *rsa.PublicKey or an *ecdsa.PublicKey.
+	Key any
+}
+
+// KeySet is an immutable set of JWT verification keys.
+type KeySet struct {
+	keys []Key
+}
+
+// NewKeySet returns a KeySet holding keys, for secrets and public keys
+// configured in code. Panics if a key is not of a supported type.
+func NewKeySet(keys ...Key) *KeySet {
+	for _, k := range keys {
+		switch k.Key.(type) {
+		case []byte, *rsa.PublicKey, *ecdsa.PublicKey:
+		default:
+			panic(fmt.Sprintf("gin-restful/auth: unsupported key type %T for key %q", k.Key, k.ID))
+		}
+	}
+	return &KeySet{keys: keys}
+}
+
+// LoadJWKS reads a JSON Web Key Set (RFC 7517) from a file.
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read JWKS: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS parses a JSON Web Key Set (RFC 7517). RSA, EC (P-256, P-384,
+// P-521) and symmetric ("oct") keys are supported; keys of other types and
+// keys meant for encryption are skipped.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("auth: parse JWKS: %w", err)
+	}
+
+	set := &KeySet{}
+	for i, raw := range doc.Keys {
+		if raw.Use == "enc" {
+			continue
+		}
+		key, err := raw.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("auth: parse JWKS key %d (%q): %w", i, raw.Kid, err)
+		}
+		if key != nil {
+			set.keys = append(set.keys, Key{ID: raw.Kid, Algorithm: raw.Alg, Key: key})
+		}
+	}
+	return set, nil
+}
+
+// candidates returns the keys that may verify a token with the given kid
+// header and algorithm.
+func (s *KeySet) candidates(kid, alg string) []Key {
+	var keys []Key
+	for _, k := range s.keys {
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		if k.Algorithm != "" && k.Algorithm != alg {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// jwk is a JSON Web Key as found in a key set document.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// oct
+	K string `json:"k"`
+}
+
+// publicKey returns the verification key of k, or nil for unsupported key
+// types.
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if _, err := key.ECDH(); err != nil {
+			return nil, err
+		}
+		return key, nil
+
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("secret: %w", err)
+		}
+		return secret, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
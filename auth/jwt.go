@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // register SHA-256 for crypto.Hash
+	_ "crypto/sha512" // register SHA-384 and SHA-512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+)
+
+// JWTConfig configures the JWT authenticator.
+type JWTConfig struct {
+	// Keys verifies token signatures. Required.
+	Keys *KeySet
+	// Issuer, when set, must equal the "iss" claim.
+	Issuer string
+	// Audience, when set, must be one of the "aud" claim's values.
+	Audience string
+	// Algorithms lists the accepted JWS algorithms. Defaults to HS256,
+	// HS384, HS512, RS256, RS384, RS512, ES256, ES384 and ES512; the key
+	// must still be of the matching type.
+	Algorithms []string
+	// ClockSkew is the leeway allowed when checking "exp" and "nbf".
+	ClockSkew time.Duration
+	// Realm is sent in WWW-Authenticate challenges. Defaults to "restricted".
+	Realm string
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+	// Principal maps verified claims to a principal. The default uses "sub"
+	// as Subject, "roles" as Roles and the space-separated "scope" (or the
+	// "scp" array) as Scopes, and keeps every claim in Claims.
+	Principal func(claims map[string]any) (*restful.Principal, error)
+}
+
+var defaultAlgorithms = []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// JWT returns an Authenticator for "Authorization: Bearer" JSON Web Tokens
+// signed with HMAC (HS*), RSA PKCS #1 v1.5 (RS*) or ECDSA (ES*) keys.
+// Panics if cfg.Keys is nil.
+func JWT(cfg JWTConfig) Authenticator {
+	if cfg.Keys == nil {
+		panic("gin-restful/auth: JWTConfig.Keys is required")
+	}
+	if cfg.Algorithms == nil {
+		cfg.Algorithms = defaultAlgorithms
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Principal == nil {
+		cfg.Principal = principalFromClaims
+	}
+	cfg.Realm = realmOrDefault(cfg.Realm)
+	return &jwtAuthenticator{cfg: cfg}
+}
+
+type jwtAuthenticator struct {
+	cfg JWTConfig
+}
+
+func (a *jwtAuthenticator) Challenge() string {
+	return fmt.Sprintf("Bearer realm=%q", a.cfg.Realm)
+}
+
+func (a *jwtAuthenticator) Authenticate(c *gin.Context) (*restful.Principal, error) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, unauthorized("invalid token: "+err.Error(),
+			fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\", error_description=%q", a.cfg.Realm, err.Error()))
+	}
+	return a.cfg.Principal(claims)
+}
+
+// verify checks the signature and registered claims of token and returns
+// its claims.
+func (a *jwtAuthenticator) verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("malformed header")
+	}
+	if !slices.Contains(a.cfg.Algorithms, header.Alg) {
+		return nil, fmt.Errorf("algorithm %q not allowed", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range a.cfg.Keys.candidates(header.Kid, header.Alg) {
+		if verifySignature(header.Alg, key.Key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("signature verification failed")
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed claims")
+	}
+	if err := a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (a *jwtAuthenticator) validateClaims(claims map[string]any) error {
+	now := a.cfg.Now()
+	if exp, ok := numericDate(claims["exp"]); ok && !now.Before(exp.Add(a.cfg.ClockSkew)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(a.cfg.ClockSkew).Before(nbf) {
+		return errors.New("token not valid yet")
+	}
+	if a.cfg.Issuer != "" && claims["iss"] != a.cfg.Issuer {
+		return errors.New("unexpected issuer")
+	}
+	if a.cfg.Audience != "" && !slices.Contains(stringList(claims["aud"]), a.cfg.Audience) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+// verifySignature reports whether signature is a valid alg signature of
+// signed under key.
+func verifySignature(alg string, key any, signed, signature []byte) bool {
+	if len(alg) != 5 {
+		return false
+	}
+	var (
+		hash      crypto.Hash
+		curveBits int
+	)
+	switch alg[2:] {
+	case "256":
+		hash, curveBits = crypto.SHA256, 256
+	case "384":
+		hash, curveBits = crypto.SHA384, 384
+	case "512":
+		hash, curveBits = crypto.SHA512, 521
+	default:
+		return false
+	}
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(signature, mac.Sum(nil))
+
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		h := hash.New()
+		h.Write(signed)
+		return rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), signature) == nil
+
+	case "ES":
+		// Each ES algorithm is bound to one curve, and its signatures are
+		// the fixed-size concatenation of r and s.
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().BitSize != curveBits {
+			return false
+		}
+		size := (curveBits + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		h := hash.New()
+		h.Write(signed)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(pub, h.Sum(nil), r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// numericDate converts a JWT NumericDate claim.
+func numericDate(v any) (time.Time, bool) {
+	seconds, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+}
+
+// stringList returns a claim that is either a string or an array of strings
+// as a slice.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func principalFromClaims(claims map[string]any) (*restful.Principal, error) {
+	p := &restful.Principal{Roles: stringList(claims["roles"]), Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = stringList(claims["scp"])
+	}
+	return p, nil
+}
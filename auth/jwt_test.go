@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// --- helpers ---
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func b64JSON(v any) string {
+	raw, _ := json.Marshal(v)
+	return b64(raw)
+}
+
+// signToken returns a JWT signed with key, which is a []byte secret, an
+// *rsa.PrivateKey or an *ecdsa.PrivateKey.
+func signToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := b64JSON(header) + "." + b64JSON(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + b64(sig)
+}
+
+func bearer(token string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":   "alice",
+		"iss":   "https://id.example.com",
+		"aud":   []string{"tasks", "billing"},
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"roles": []string{"admin"},
+		"scope": "tasks:read tasks:write",
+	}
+}
+
+// --- tests ---
+
+func TestJWT_HS256(t *testing.T) {
+	secret := []byte("shared-secret")
+	engine := setupAuthRouter(Middleware(JWT(JWTConfig{
+		Keys:     NewKeySet(Key{Key: secret}),
+		Issuer:   "https://id.example.com",
+		Audience: "tasks",
+		Now:      func() time.Time { return testNow },
+		Realm:    "api",
+	})))
+
+	w := doAuthRequest(engine, bearer(signToken(t, "HS256", "", secret, validClaims())))
+	if w.Code != http.StatusOK || w.Body.String() != `{"subject":"alice"}` {
+		t.Fatalf("expected valid token to authenticate, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doAuthRequest(engine, bearer(signToken(t, "HS256", "", []byte("other"), validClaims())))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad signature, got %d", w.Code)
+	}
+	want := `Bearer realm="api", error="invalid_token", error_description="signature verification failed"`
+	if got := w.Header().Get("WWW-Authenticate"); got != want {
+		t.Errorf("unexpected challenge: %q", got)
+	}
+}
+
+func TestJWT_DefaultPrincipal(t *testing.T) {
+	p, err := principalFromClaims(map[string]any{"sub": "alice", "roles": []any{"admin"}, "scope": "a b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "alice" || !p.HasRole("admin") || !p.HasScope("b") {
+		t.Errorf("unexpected principal: %+v", p)
+	}
+	if p, _ := principalFromClaims(map[string]any{"scp": []any{"x"}}); !p.HasScope("x") {
+		t.Errorf("expected scp claim to be used, got %+v", p)
+	}
+}
+
+func TestJWT_ClaimValidation(t *testing.T) {
+	secret := []byte("shared-secret")
+	engine := setupAuthRouter(Middleware(JWT(JWTConfig{
+		Keys:      NewKeySet(Key{Key: secret}),
+		Issuer:    "https://id.example.com",
+		Audience:  "tasks",
+		ClockSkew: time.Minute,
+		Now:       func() time.Time { return testNow },
+	})))
+
+	tests := []struct {
+		name   string
+		modify func(map[string]any)
+		want   int
+	}{
+		{"expired within skew", func(c map[string]any) { c["exp"] = testNow.Add(-30 * time.Second).Unix() }, http.StatusOK},
+		{"expired", func(c map[string]any) { c["exp"] = testNow.Add(-2 * time.Minute).Unix() }, http.StatusUnauthorized},
+		{"not yet valid", func(c map[string]any) { c["nbf"] = testNow.Add(2 * time.Minute).Unix() }, http.StatusUnauthorized},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }, http.StatusUnauthorized},
+		{"wrong audience", func(c map[string]any) { c["aud"] = "billing" }, http.StatusUnauthorized},
+		{"string audience", func(c map[string]any) { c["aud"] = "tasks" }, http.StatusOK},
+	}
+	for _, tt := range tests {
+		claims := validClaims()
+		tt.modify(claims)
+		if w := doAuthRequest(engine, bearer(signToken(t, "HS256", "", secret, claims))); w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d (%s)", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestJWT_RejectsNoneAndKeyConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := setupAuthRouter(Middleware(JWT(JWTConfig{
+		Keys: NewKeySet(Key{Key: &rsaKey.PublicKey}),
+		Now:  func() time.Time { return testNow },
+	})))
+
+	unsigned := b64JSON(map[string]string{"alg": "none"}) + "." + b64JSON(validClaims()) + "."
+	if w := doAuthRequest(engine, bearer(unsigned)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected alg none to be rejected, got %d", w.Code)
+	}
+
+	// An HS256 token "signed" with public key material must not verify.
+	modulus := rsaKey.PublicKey.N.Bytes()
+	if w := doAuthRequest(engine, bearer(signToken(t, "HS256", "", modulus, validClaims()))); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected HS256 with an RSA key to be rejected, got %d", w.Code)
+	}
+	if w := doAuthRequest(engine, bearer(signToken(t, "RS256", "", rsaKey, validClaims()))); w.Code != http.StatusOK {
+		t.Errorf("expected RS256 token to authenticate, got %d", w.Code)
+	}
+}
+
+func TestJWT_JWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "use": "sig", "n": %q, "e": %q},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": %q, "y": %q},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": "AA"}
+	]}`,
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.FillBytes(make([]byte, 32))), b64(ecKey.Y.FillBytes(make([]byte, 32))),
+	)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(jwks), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("failed to load JWKS: %v", err)
+	}
+	if len(keys.keys) != 2 {
+		t.Fatalf("expected 2 signing keys, got %d", len(keys.keys))
+	}
+	engine := setupAuthRouter(Middleware(JWT(JWTConfig{Keys: keys, Now: func() time.Time { return testNow }})))
+
+	tests := []struct {
+		name string
+		tok  string
+		want int
+	}{
+		{"RS256 by kid", signToken(t, "RS256", "rsa-1", rsaKey, validClaims()), http.StatusOK},
+		{"ES256 by kid", signToken(t, "ES256", "ec-1", ecKey, validClaims()), http.StatusOK},
+		{"ES256 without kid", signToken(t, "ES256", "", ecKey, validClaims()), http.StatusOK},
+		{"unknown kid", signToken(t, "ES256", "ec-2", ecKey, validClaims()), http.StatusUnauthorized},
+		{"kid of another key", signToken(t, "RS256", "ec-1", rsaKey, validClaims()), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if w := doAuthRequest(engine, bearer(tt.tok)); w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d (%s)", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestParseJWKS_InvalidKey(t *testing.T) {
+	_, err := ParseJWKS([]byte(`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+	if err == nil || !strings.Contains(err.Error(), "key 0") {
+		t.Errorf("expected an error for an invalid EC point, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package restful
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+const principalKey = "gin-restful.principal"
+
+// Principal is the authenticated caller of a request. Authentication
+// middleware stores it with SetPrincipal; policies and Authorizers read it
+// back with PrincipalFrom.
+type Principal struct {
+	// Subject identifies the caller, e.g. a user id or API key name.
+	Subject string
+	Roles   []string
+	Scopes  []string
+	// Claims holds any further attributes of the caller, such as the claims
+	// of a verified token.
+	Claims map[string]any
+}
+
+// HasRole reports whether p has the given role. It is false for a nil
+// Principal.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// HasScope reports whether p has the given scope. It is false for a nil
+// Principal.
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// SetPrincipal stores the authenticated caller on the request context.
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalKey, p)
+}
+
+// PrincipalFrom returns the principal stored with SetPrincipal.
+func PrincipalFrom(c *gin.Context) (*Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := v.(*Principal)
+	return p, ok && p != nil
+}
+
+// Requirement describes who may perform an operation. The zero value allows
+// any authenticated principal.
+type Requirement struct {
+	// Public allows the operation without a principal.
+	Public bool
+	// Roles lists accepted roles; the principal needs at least one of them.
+	Roles []string
+	// Scopes lists required scopes; the principal needs all of them.
+	Scopes []string
+}
+
+// Policy declares the Requirement of each operation of a resource.
+// Create covers Post and BulkPost, Update covers Put, Patch and BulkPatch,
+// and Delete covers Delete and BulkDelete. Custom actions are looked up in
+// Actions by name; actions without an entry use Default, as do operations
+// whose field is nil.
+type Policy struct {
+	Default *Requirement
+	List    *Requirement
+	Get     *Requirement
+	Create  *Requirement
+	Update  *Requirement
+	Delete  *Requirement
+	Actions map[string]Requirement
+}
+
+// requirement returns the Requirement for the named operation.
+func (p Policy) requirement(name string) Requirement {
+	req := forOperation(name, p.Default, p.List, p.Get, p.Create, p.Update, p.Delete, p.Actions)
+	if req == nil {
+		return Requirement{}
+	}
+	return *req
+}
+
+// forOperation returns the declaration among those of a per-operation
+// policy that applies to the named operation, falling back to def, or nil.
+// Create covers Post and BulkPost, Update covers Put, Patch and BulkPatch,
+// Delete covers Delete and BulkDelete, and custom actions are looked up in
+// actions.
+func forOperation[T any](name string, def, list, get, create, update, del *T, actions map[string]T) *T {
+	var v *T
+	switch name {
+	case "List":
+		v = list
+	case "Get":
+		v = get
+	case "Post", "BulkPost":
+		v = create
+	case "Put", "Patch", "BulkPatch":
+		v = update
+	case "Delete", "BulkDelete":
+		v = del
+	default:
+		if a, ok := actions[name]; ok {
+			v = &a
+		}
+	}
+	if v == nil {
+		v = def
+	}
+	return v
+}
+
+// PolicyProvider is implemented by resources that declare an authorization
+// policy. Every operation of such a resource requires an authenticated
+// principal unless its Requirement is Public.
+type PolicyProvider interface {
+	Policy() Policy
+}
+
+// Authorizer decides whether principal may perform op. It is called for
+// every operation of a resource implementing PolicyProvider that is not
+// Public, with a non-nil principal and the operation's Requirement.
+// Returning an error denies the request: *HTTPError values are sent as is,
+// any other error is recorded with c.Error and becomes 403 Forbidden.
+type Authorizer interface {
+	Authorize(principal *Principal, op Operation, req Requirement, c *gin.Context) error
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(principal *Principal, op Operation, req Requirement, c *gin.Context) error
+
+// Authorize calls f.
+func (f AuthorizerFunc) Authorize(principal *Principal, op Operation, req Requirement, c *gin.Context) error {
+	return f(principal, op, req, c)
+}
+
+// WithAuthorizer replaces the default Authorizer, which checks the roles and
+// scopes of the Requirement, e.g. to consult an external policy engine or to
+// check ownership of the resource identified by op.ID.
+func WithAuthorizer(authorizer Authorizer) APIOption {
+	return func(api *API) {
+		api.authorizer = authorizer
+	}
+}
+
+// authorizeRequirement is the default Authorizer. It allows principals that
+// have one of the required roles, if any, and all of the required scopes.
+func authorizeRequirement(principal *Principal, op Operation, req Requirement, c *gin.Context) error {
+	if len(req.Roles) > 0 && !slices.ContainsFunc(req.Roles, principal.HasRole) {
+		return Abort(http.StatusForbidden, "insufficient role", WithCode("FORBIDDEN"))
+	}
+	for _, scope := range req.Scopes {
+		if !principal.HasScope(scope) {
+			return Abort(http.StatusForbidden, "insufficient scope", WithCode("FORBIDDEN"),
+				WithDetails(map[string]string{"scope": scope}))
+		}
+	}
+	return nil
+}
+
+// withPolicy enforces the policy of entry's resource, if any, before fn.
+func (api *API) withPolicy(entry *resourceEntry, name string, fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	authorize := api.authorization(entry, name)
+	if authorize == nil {
+		return fn
+	}
+
+	return func(c *gin.Context) (any, int, error) {
+		if err := authorize(c); err != nil {
+			return nil, 0, err
+		}
+		return fn(c)
+	}
+}
+
+// authorization returns the check of the policy of entry's resource for the
+// named operation, or nil if the operation is not restricted. The check
+// reads the operation with GetOperation.
+func (api *API) authorization(entry *resourceEntry, name string) func(c *gin.Context) error {
+	provider, ok := entry.resource.(PolicyProvider)
+	if !ok {
+		return nil
+	}
+	req := provider.Policy().requirement(name)
+	if req.Public {
+		return nil
+	}
+	authorizer := api.authorizer
+	if authorizer == nil {
+		authorizer = AuthorizerFunc(authorizeRequirement)
+	}
+
+	return func(c *gin.Context) error {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			return Abort(http.StatusUnauthorized, "authentication required", WithCode("UNAUTHORIZED"))
+		}
+		op, _ := GetOperation(c)
+		if err := authorizer.Authorize(principal, op, req, c); err != nil {
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) {
+				return err
+			}
+			_ = c.Error(err)
+			return Abort(http.StatusForbidden, "forbidden", WithCode("FORBIDDEN"))
+		}
+		return nil
+	}
+}
+
+// RowFilter is implemented by resources that restrict which items of their
+// Lister results a caller may see. FilterRow is called for each element of a
+// slice returned by List, or each item of a Stream returned by StreamList,
+// with the request's principal (nil for anonymous requests); elements for
+// which it returns false are dropped. Results that are not slices are
+// passed through unchanged.
+type RowFilter interface {
+	FilterRow(principal *Principal, item any, c *gin.Context) bool
+}
+
+// filterRows applies the RowFilter of entry's resource to the results of fn.
+func filterRows(entry *resourceEntry, fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	filter, ok := entry.resource.(RowFilter)
+	if !ok {
+		return fn
+	}
+
+	return func(c *gin.Context) (any, int, error) {
+		result, status, err := fn(c)
+		if err != nil || c.IsAborted() {
+			return result, status, err
+		}
+		principal, _ := PrincipalFrom(c)
+		if stream, ok := result.(Stream); ok {
+			return Stream(func(yield func(any, error) bool) {
+				for item, err := range stream {
+					if err == nil && !filter.FilterRow(principal, item, c) {
+						continue
+					}
+					if !yield(item, err) {
+						return
+					}
+				}
+			}), status, nil
+		}
+		v := reflect.ValueOf(result)
+		if v.Kind() != reflect.Slice {
+			return result, status, err
+		}
+
+		kept := reflect.MakeSlice(v.Type(), 0, v.Len())
+		for i := range v.Len() {
+			if filter.FilterRow(principal, v.Index(i).Interface(), c) {
+				kept = reflect.Append(kept, v.Index(i))
+			}
+		}
+		return kept.Interface(), status, nil
+	}
+}
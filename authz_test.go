@@ -0,0 +1,174 @@
+package restful
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type note struct {
+	ID    string `json:"id"`
+	Owner string `json:"owner"`
+}
+
+type noteResource struct{}
+
+func (r *noteResource) List(c *gin.Context) (any, int, error) {
+	return []note{{ID: "1", Owner: "alice"}, {ID: "2", Owner: "bob"}, {ID: "3", Owner: "alice"}}, http.StatusOK, nil
+}
+
+func (r *noteResource) Get(id string, c *gin.Context) (any, int, error) {
+	return note{ID: id, Owner: "alice"}, http.StatusOK, nil
+}
+
+func (r *noteResource) Post(c *gin.Context) (any, int, error) {
+	return note{ID: "4"}, http.StatusCreated, nil
+}
+
+func (r *noteResource) Delete(id string, c *gin.Context) (any, int, error) {
+	return nil, http.StatusNoContent, nil
+}
+
+func (r *noteResource) Actions() []Action {
+	return []Action{
+		ItemAction(http.MethodPost, "archive", func(id string, c *gin.Context) (any, int, error) {
+			return gin.H{"archived": id}, http.StatusOK, nil
+		}),
+	}
+}
+
+func (r *noteResource) Policy() Policy {
+	return Policy{
+		Get:     &Requirement{Public: true},
+		Create:  &Requirement{Scopes: []string{"notes:write"}},
+		Delete:  &Requirement{Roles: []string{"admin", "owner"}},
+		Actions: map[string]Requirement{"archive": {Roles: []string{"admin"}}},
+	}
+}
+
+func (r *noteResource) FilterRow(principal *Principal, item any, c *gin.Context) bool {
+	return principal.HasRole("admin") || item.(note).Owner == principal.Subject
+}
+
+// --- helpers ---
+
+// principalMiddleware authenticates "X-User: name;role,role;scope,scope"
+// headers.
+func principalMiddleware(c *gin.Context) {
+	header := c.GetHeader("X-User")
+	if header == "" {
+		return
+	}
+	parts := strings.Split(header, ";")
+	p := &Principal{Subject: parts[0]}
+	if len(parts) > 1 && parts[1] != "" {
+		p.Roles = strings.Split(parts[1], ",")
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		p.Scopes = strings.Split(parts[2], ",")
+	}
+	SetPrincipal(c, p)
+}
+
+func setupAuthzRouter(opts ...APIOption) *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", opts...)
+	api.Use(principalMiddleware)
+	api.AddResource("/notes", &noteResource{})
+	return engine
+}
+
+func doAuthzRequest(engine *gin.Engine, method, path, user string) int {
+	return doRequestWithHeaders(engine, method, path, map[string]string{"X-User": user}).Code
+}
+
+// --- tests ---
+
+func TestPolicy_RequiresPrincipal(t *testing.T) {
+	engine := setupAuthzRouter()
+
+	w := doRequest(engine, "GET", "/api/notes", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	var resp HTTPError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp.Code != "UNAUTHORIZED" {
+		t.Errorf("expected UNAUTHORIZED code, got %q", resp.Code)
+	}
+}
+
+func TestPolicy_PublicOperation(t *testing.T) {
+	engine := setupAuthzRouter()
+
+	if code := doAuthzRequest(engine, "GET", "/api/notes/1", ""); code != http.StatusOK {
+		t.Errorf("expected public Get to succeed anonymously, got %d", code)
+	}
+}
+
+func TestPolicy_RolesAndScopes(t *testing.T) {
+	engine := setupAuthzRouter()
+
+	tests := []struct {
+		method, path, user string
+		want               int
+	}{
+		{"POST", "/api/notes", "alice;;notes:read", http.StatusForbidden},
+		{"POST", "/api/notes", "alice;;notes:read,notes:write", http.StatusCreated},
+		{"DELETE", "/api/notes/1", "alice;viewer", http.StatusForbidden},
+		{"DELETE", "/api/notes/1", "alice;owner", http.StatusNoContent},
+		{"POST", "/api/notes/1/archive", "alice;owner", http.StatusForbidden},
+		{"POST", "/api/notes/1/archive", "root;admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := doAuthzRequest(engine, tt.method, tt.path, tt.user); code != tt.want {
+			t.Errorf("%s %s as %q: expected %d, got %d", tt.method, tt.path, tt.user, tt.want, code)
+		}
+	}
+}
+
+func TestPolicy_CustomAuthorizer(t *testing.T) {
+	var seen Operation
+	engine := setupAuthzRouter(WithAuthorizer(AuthorizerFunc(func(p *Principal, op Operation, req Requirement, c *gin.Context) error {
+		seen = op
+		if op.ID == "2" {
+			return errors.New("not the owner")
+		}
+		return nil
+	})))
+
+	if code := doAuthzRequest(engine, "DELETE", "/api/notes/1", "alice"); code != http.StatusNoContent {
+		t.Errorf("expected custom authorizer to replace role checks, got %d", code)
+	}
+	if seen.Name != "Delete" || seen.ID != "1" {
+		t.Errorf("unexpected operation: %+v", seen)
+	}
+
+	w := doRequestWithHeaders(engine, "DELETE", "/api/notes/2", map[string]string{"X-User": "alice"})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "FORBIDDEN") {
+		t.Errorf("expected plain errors to become 403, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRowFilter_FiltersListResults(t *testing.T) {
+	engine := setupAuthzRouter()
+
+	for user, want := range map[string]int{"alice": 2, "bob": 1, "root;admin": 3} {
+		w := doRequestWithHeaders(engine, "GET", "/api/notes", map[string]string{"X-User": user})
+		var notes []note
+		if err := json.Unmarshal(w.Body.Bytes(), &notes); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(notes) != want {
+			t.Errorf("%s: expected %d notes, got %d", user, want, len(notes))
+		}
+	}
+}
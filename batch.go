@@ -0,0 +1,396 @@
+package restful
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultBatchMaxRequests is the maximum number of sub-requests accepted by a
+// batch endpoint unless WithBatchMaxRequests is used.
+const DefaultBatchMaxRequests = 20
+
+// BatchOption configures a batch endpoint created by EnableBatch.
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	maxRequests int
+	parallelism int
+}
+
+// WithBatchMaxRequests limits the number of sub-requests in one batch.
+// Larger batches are rejected with 413 Request Entity Too Large.
+func WithBatchMaxRequests(n int) BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.maxRequests = n
+	}
+}
+
+// WithBatchParallel executes up to n independent sub-requests concurrently.
+// Sub-requests that depend on others still wait for them. By default
+// sub-requests run sequentially in the order given.
+func WithBatchParallel(n int) BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.parallelism = n
+	}
+}
+
+// BatchRequest is one sub-request of a batch.
+type BatchRequest struct {
+	// ID names the sub-request so that later sub-requests can depend on it.
+	ID        string            `json:"id,omitempty"`
+	Method    string            `json:"method" binding:"required"`
+	Path      string            `json:"path" binding:"required"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body,omitempty"`
+	DependsOn []string          `json:"depends_on,omitempty"`
+}
+
+// BatchResponse is the result of one sub-request of a batch.
+type BatchResponse struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// batchReference matches "${id.body.path}" references to earlier responses.
+var batchReference = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+)*)\}`)
+
+type batchKeysKey struct{}
+
+// EnableBatch registers a POST endpoint at path (below the API prefix) that
+// executes a JSON array of sub-requests against the resources registered on
+// this API and returns a JSON array of their responses, in request order:
+//
+//	POST /api/batch
+//	[{"id": "new", "method": "POST", "path": "/api/posts", "body": {"title": "hi"}},
+//	 {"method": "GET", "path": "/api/posts/${new.body.id}"}]
+//
+// Sub-requests inherit the outer request's headers, except Idempotency-Key,
+// and the values set on its gin context (e.g. by authentication
+// middleware), so they run with the same auth context. A
+// "${id.body.field}" reference in a path, header or body string is replaced
+// with a value from an earlier response and makes the sub-request depend on
+// it; "depends_on" declares further dependencies. Sub-requests whose
+// dependencies fail are answered with 424 Failed Dependency instead of being
+// executed. A sub-request whose handler panics is answered with 500, and its
+// *PanicError is added to the batch request's errors.
+//
+// Sub-requests go through the same handlers as direct requests, including
+// the middleware of the *gin.Engine or *gin.RouterGroup the API was created
+// with, so guards on the router apply to them too. EnableBatch panics if
+// the API was created with another gin.IRouter, whose middleware it cannot
+// run.
+func (api *API) EnableBatch(path string, opts ...BatchOption) {
+	if _, _, ok := routerScope(api.router); !ok {
+		panic(fmt.Sprintf("gin-restful: EnableBatch requires a *gin.Engine or *gin.RouterGroup router, got %T", api.router))
+	}
+	cfg := batchConfig{maxRequests: DefaultBatchMaxRequests, parallelism: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var (
+		mu     sync.Mutex
+		engine *gin.Engine
+		routes int
+	)
+	// The dispatcher is rebuilt when routes have been registered since it
+	// was last built, so that resources added after EnableBatch, or after
+	// the first batch, are reachable too.
+	dispatcher := func() *gin.Engine {
+		mu.Lock()
+		defer mu.Unlock()
+		if engine != nil && routes == len(api.handlers) {
+			return engine
+		}
+		engine = gin.New()
+		engine.Use(func(c *gin.Context) {
+			if keys, ok := c.Request.Context().Value(batchKeysKey{}).(map[any]any); ok {
+				for k, v := range keys {
+					c.Set(k, v)
+				}
+			}
+		})
+		for _, rh := range api.handlers {
+			engine.Handle(rh.method, rh.path, rh.handlers...)
+		}
+		routes = len(api.handlers)
+		return engine
+	}
+
+	handler := makeHandlerWithErrorHandler(func(c *gin.Context) (any, int, error) {
+		var reqs []BatchRequest
+		if err := c.ShouldBindJSON(&reqs); err != nil {
+			return nil, 0, Abort(http.StatusBadRequest, "batch body must be a JSON array of requests")
+		}
+		if len(reqs) > cfg.maxRequests {
+			return nil, 0, Abort(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("batch contains %d requests, the maximum is %d", len(reqs), cfg.maxRequests),
+				WithCode("BATCH_TOO_LARGE"),
+			)
+		}
+		deps, err := batchDependencies(reqs)
+		if err != nil {
+			return nil, 0, err
+		}
+		return runBatch(c, dispatcher(), reqs, deps, cfg.parallelism), http.StatusOK, nil
+	}, api.errorHandler)
+
+	var handlers []gin.HandlerFunc
+	if api.requestID != nil {
+		handlers = append(handlers, api.assignRequestID)
+	}
+	handlers = append(append(handlers, api.middlewareChain()...), handler)
+	api.router.POST(normalizePath(api.prefix+path), handlers...)
+}
+
+// batchDependencies returns the indexes each sub-request depends on. A
+// sub-request may only depend on sub-requests listed before it, which rules
+// out cycles.
+func batchDependencies(reqs []BatchRequest) ([][]int, error) {
+	index := make(map[string]int, len(reqs))
+	deps := make([][]int, len(reqs))
+	for i, req := range reqs {
+		names := append([]string(nil), req.DependsOn...)
+		text := req.Path + string(req.Body)
+		for _, v := range req.Headers {
+			text += v
+		}
+		for _, m := range batchReference.FindAllStringSubmatch(text, -1) {
+			names = append(names, m[1])
+		}
+		for _, name := range names {
+			j, ok := index[name]
+			if !ok {
+				return nil, Abort(http.StatusBadRequest,
+					fmt.Sprintf("batch request %d depends on unknown or later request %q", i, name))
+			}
+			deps[i] = append(deps[i], j)
+		}
+		if req.ID != "" {
+			if _, dup := index[req.ID]; dup {
+				return nil, Abort(http.StatusBadRequest, fmt.Sprintf("duplicate batch request id %q", req.ID))
+			}
+			index[req.ID] = i
+		}
+	}
+	return deps, nil
+}
+
+func runBatch(c *gin.Context, engine *gin.Engine, reqs []BatchRequest, deps [][]int, parallelism int) []BatchResponse {
+	keys := make(map[any]any, len(c.Keys))
+	for k, v := range c.Keys {
+		keys[k] = v
+	}
+	ctx := context.WithValue(c.Request.Context(), batchKeysKey{}, keys)
+
+	resps := make([]BatchResponse, len(reqs))
+	panics := make([]error, len(reqs))
+	done := make([]chan struct{}, len(reqs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	byID := func(id string) *BatchResponse {
+		for i := range reqs {
+			if reqs[i].ID == id {
+				return &resps[i]
+			}
+		}
+		return nil
+	}
+
+	run := func(i int) {
+		defer close(done[i])
+		for _, j := range deps[i] {
+			<-done[j]
+			if resps[j].Status >= http.StatusBadRequest {
+				resps[i] = batchFailure(reqs[i].ID, http.StatusFailedDependency,
+					fmt.Sprintf("dependency %q failed", reqs[j].ID))
+				return
+			}
+		}
+		resps[i], panics[i] = dispatchBatchRequest(ctx, c.Request, engine, reqs[i], byID)
+	}
+	report := func() []BatchResponse {
+		for _, err := range panics {
+			if err != nil {
+				_ = c.Error(err)
+			}
+		}
+		return resps
+	}
+
+	if parallelism <= 1 {
+		for i := range reqs {
+			run(i)
+		}
+		return report()
+	}
+
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Wait for dependencies before taking a slot so that waiting
+			// sub-requests cannot starve the ones they depend on.
+			for _, j := range deps[i] {
+				<-done[j]
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			run(i)
+		}()
+	}
+	wg.Wait()
+	return report()
+}
+
+// dispatchBatchRequest executes breq on engine. A panic while handling it is
+// answered with 500 and returned as a *PanicError, as sub-requests may run
+// in goroutines of their own, out of reach of the outer engine's recovery.
+func dispatchBatchRequest(ctx context.Context, outer *http.Request, engine *gin.Engine, breq BatchRequest, byID func(string) *BatchResponse) (resp BatchResponse, panicErr error) {
+	resolve := func(s string, escape bool) string {
+		return batchReference.ReplaceAllStringFunc(s, func(ref string) string {
+			m := batchReference.FindStringSubmatch(ref)
+			value := batchLookup(byID(m[1]), strings.Split(strings.TrimPrefix(m[2], "."), "."))
+			if escape {
+				quoted, _ := json.Marshal(value)
+				return string(quoted[1 : len(quoted)-1])
+			}
+			return value
+		})
+	}
+
+	var body []byte
+	if len(breq.Body) > 0 && !bytes.Equal(breq.Body, []byte("null")) {
+		body = []byte(resolve(string(breq.Body), true))
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(breq.Method), resolve(breq.Path, false), bytes.NewReader(body))
+	if err != nil {
+		return batchFailure(breq.ID, http.StatusBadRequest, "invalid batch request"), nil
+	}
+	for name, values := range outer.Header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Content-Type", "Accept-Encoding", IdempotencyKeyHeader:
+			continue
+		}
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", gin.MIMEJSON)
+	}
+	for name, value := range breq.Headers {
+		req.Header.Set(name, resolve(value, false))
+	}
+	req.RemoteAddr = outer.RemoteAddr
+
+	defer func() {
+		if v := recover(); v != nil {
+			resp = batchFailure(breq.ID, http.StatusInternalServerError, "internal server error")
+			panicErr = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	w := newBatchRecorder()
+	engine.ServeHTTP(w, req)
+
+	resp = BatchResponse{ID: breq.ID, Status: w.status, Headers: make(map[string]string, len(w.header))}
+	for name := range w.header {
+		resp.Headers[name] = w.header.Get(name)
+	}
+	if w.body.Len() > 0 {
+		if json.Valid(w.body.Bytes()) {
+			resp.Body = w.body.Bytes()
+		} else {
+			resp.Body, _ = json.Marshal(w.body.String())
+		}
+	}
+	return resp, nil
+}
+
+// batchLookup resolves a path such as ["body", "id"] against resp.
+func batchLookup(resp *BatchResponse, path []string) string {
+	if resp == nil || len(path) == 0 {
+		return ""
+	}
+	switch path[0] {
+	case "status":
+		return strconv.Itoa(resp.Status)
+	case "headers":
+		if len(path) == 2 {
+			return resp.Headers[http.CanonicalHeaderKey(path[1])]
+		}
+		return ""
+	case "body":
+	default:
+		return ""
+	}
+
+	var value any
+	if err := json.Unmarshal(resp.Body, &value); err != nil {
+		return ""
+	}
+	for _, key := range path[1:] {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return ""
+			}
+			value = v[i]
+		default:
+			return ""
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+}
+
+func batchFailure(id string, status int, message string) BatchResponse {
+	body, _ := json.Marshal(&HTTPError{Status: status, Message: message})
+	return BatchResponse{ID: id, Status: status, Body: body}
+}
+
+// batchRecorder is a minimal http.ResponseWriter that buffers a sub-response.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *batchRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *batchRecorder) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *batchRecorder) WriteHeader(status int) {
+	w.status = status
+}
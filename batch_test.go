@@ -0,0 +1,290 @@
+package restful
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type batchNoteResource struct {
+	mu    sync.Mutex
+	notes map[string]string
+}
+
+func (r *batchNoteResource) Post(c *gin.Context) (any, int, error) {
+	body, err := Bind[struct {
+		Text string `json:"text" binding:"required"`
+	}](c)
+	if err != nil {
+		return nil, 0, Abort(http.StatusBadRequest, err.Error())
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := strconv.Itoa(len(r.notes) + 1)
+	r.notes[id] = body.Text
+	return gin.H{"id": id, "text": body.Text, "owner": c.GetString("user_id")}, http.StatusCreated, nil
+}
+
+func (r *batchNoteResource) Get(id string, c *gin.Context) (any, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	text, ok := r.notes[id]
+	if !ok {
+		return nil, 0, Abort(http.StatusNotFound, "note not found")
+	}
+	return gin.H{"id": id, "text": text, "auth": c.GetHeader("Authorization")}, http.StatusOK, nil
+}
+
+// --- helpers ---
+
+func setupBatchRouter(opts ...BatchOption) *gin.Engine {
+	engine := gin.New()
+	group := engine.Group("/", func(c *gin.Context) {
+		c.Set("user_id", "alice")
+		c.Next()
+	})
+	api := NewAPI(group, "/api")
+	api.EnableBatch("/batch", opts...)
+	api.AddResource("/notes", &batchNoteResource{notes: map[string]string{"1": "first"}})
+	return engine
+}
+
+func decodeBatch(t *testing.T, body []byte) []BatchResponse {
+	t.Helper()
+	var resps []BatchResponse
+	if err := json.Unmarshal(body, &resps); err != nil {
+		t.Fatalf("failed to parse response: %v (%s)", err, body)
+	}
+	return resps
+}
+
+func doRequestWithAuth(engine *gin.Engine, path, body, auth string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", auth)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// --- tests ---
+
+func TestBatch_DispatchesSubRequests(t *testing.T) {
+	engine := setupBatchRouter()
+
+	w := doRequest(engine, "POST", "/api/batch", `[
+		{"method": "GET", "path": "/api/notes/1"},
+		{"method": "GET", "path": "/api/notes/9"},
+		{"method": "GET", "path": "/elsewhere"}
+	]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resps := decodeBatch(t, w.Body.Bytes())
+	if len(resps) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(resps))
+	}
+	if resps[0].Status != http.StatusOK || resps[1].Status != http.StatusNotFound || resps[2].Status != http.StatusNotFound {
+		t.Errorf("unexpected statuses: %d %d %d", resps[0].Status, resps[1].Status, resps[2].Status)
+	}
+	if resps[0].Headers["Content-Type"] == "" {
+		t.Error("expected sub-response headers")
+	}
+}
+
+func TestBatch_InheritsOuterAuthContext(t *testing.T) {
+	engine := setupBatchRouter()
+
+	req := `[{"id":"new","method":"POST","path":"/api/notes","body":{"text":"hi"}},
+		{"method":"GET","path":"/api/notes/${new.body.id}"}]`
+	w := doRequestWithAuth(engine, "/api/batch", req, "Bearer token")
+	resps := decodeBatch(t, w.Body.Bytes())
+
+	var created, fetched map[string]string
+	_ = json.Unmarshal(resps[0].Body, &created)
+	_ = json.Unmarshal(resps[1].Body, &fetched)
+	if created["owner"] != "alice" {
+		t.Errorf("expected outer context value, got %v", created)
+	}
+	if fetched["text"] != "hi" || fetched["auth"] != "Bearer token" {
+		t.Errorf("expected dependent GET with outer headers, got %v", fetched)
+	}
+}
+
+func TestBatch_FailedDependency(t *testing.T) {
+	engine := setupBatchRouter()
+
+	w := doRequest(engine, "POST", "/api/batch", `[
+		{"id":"bad","method":"POST","path":"/api/notes","body":{}},
+		{"method":"GET","path":"/api/notes/${bad.body.id}"},
+		{"method":"GET","path":"/api/notes/1","depends_on":["bad"]}
+	]`)
+	resps := decodeBatch(t, w.Body.Bytes())
+	if resps[0].Status != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resps[0].Status)
+	}
+	if resps[1].Status != http.StatusFailedDependency || resps[2].Status != http.StatusFailedDependency {
+		t.Errorf("expected 424 for dependents, got %d %d", resps[1].Status, resps[2].Status)
+	}
+}
+
+func TestBatch_UnknownDependency_Returns400(t *testing.T) {
+	engine := setupBatchRouter()
+
+	w := doRequest(engine, "POST", "/api/batch", `[
+		{"method":"GET","path":"/api/notes/${later.body.id}"},
+		{"id":"later","method":"GET","path":"/api/notes/1"}
+	]`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestBatch_MaxRequests_Returns413(t *testing.T) {
+	engine := setupBatchRouter(WithBatchMaxRequests(1))
+
+	w := doRequest(engine, "POST", "/api/batch", `[
+		{"method":"GET","path":"/api/notes/1"},
+		{"method":"GET","path":"/api/notes/1"}
+	]`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestBatch_Parallel(t *testing.T) {
+	engine := setupBatchRouter(WithBatchParallel(4))
+
+	w := doRequest(engine, "POST", "/api/batch", `[
+		{"id":"a","method":"POST","path":"/api/notes","body":{"text":"a"}},
+		{"method":"GET","path":"/api/notes/1"},
+		{"method":"GET","path":"/api/notes/1"},
+		{"method":"GET","path":"/api/notes/${a.body.id}"}
+	]`)
+	resps := decodeBatch(t, w.Body.Bytes())
+	for i, resp := range resps {
+		want := http.StatusOK
+		if i == 0 {
+			want = http.StatusCreated
+		}
+		if resp.Status != want {
+			t.Errorf("response %d: expected %d, got %d", i, want, resp.Status)
+		}
+	}
+}
+
+func TestBatch_InvalidBody_Returns400(t *testing.T) {
+	engine := setupBatchRouter()
+
+	w := doRequest(engine, "POST", "/api/batch", `{"method":"GET"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestBatch_PanickingSubRequest(t *testing.T) {
+	for _, parallelism := range []int{1, 2} {
+		engine := gin.New()
+		var errs []error
+		engine.Use(func(c *gin.Context) {
+			c.Next()
+			for _, err := range c.Errors {
+				errs = append(errs, err.Err)
+			}
+		})
+		api := NewAPI(engine, "/api")
+		api.EnableBatch("/batch", WithBatchParallel(parallelism))
+		api.AddResource("/notes", &batchNoteResource{notes: map[string]string{"1": "first"}})
+		api.AddResource("/broken", &panickingResource{})
+
+		w := doRequest(engine, "POST", "/api/batch", `[
+			{"method":"GET","path":"/api/broken/1"},
+			{"method":"GET","path":"/api/notes/1"}
+		]`)
+		resps := decodeBatch(t, w.Body.Bytes())
+		if w.Code != http.StatusOK || len(resps) != 2 || resps[0].Status != http.StatusInternalServerError || resps[1].Status != http.StatusOK {
+			t.Errorf("parallelism %d: expected a 500 for the panicking sub-request only, got %d %s", parallelism, w.Code, w.Body.String())
+		}
+		var panicErr *PanicError
+		if len(errs) != 1 || !errors.As(errs[0], &panicErr) {
+			t.Errorf("parallelism %d: expected the panic to be reported, got %v", parallelism, errs)
+		}
+	}
+}
+
+func TestBatch_ReachesResourcesAddedLater(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.EnableBatch("/batch")
+	api.AddResource("/notes", &batchNoteResource{notes: map[string]string{"1": "first"}})
+	doRequest(engine, "POST", "/api/batch", `[{"method":"GET","path":"/api/notes/1"}]`)
+
+	api.AddResource("/drafts", &batchNoteResource{notes: map[string]string{"1": "draft"}})
+	w := doRequest(engine, "POST", "/api/batch", `[{"method":"GET","path":"/api/drafts/1"}]`)
+	if resps := decodeBatch(t, w.Body.Bytes()); len(resps) != 1 || resps[0].Status != http.StatusOK {
+		t.Errorf("expected the new resource to be reachable, got %s", w.Body.String())
+	}
+}
+
+type batchDeleteResource struct {
+	deleted atomic.Int32
+}
+
+func (r *batchDeleteResource) Delete(id string, c *gin.Context) (any, int, error) {
+	r.deleted.Add(1)
+	return nil, http.StatusNoContent, nil
+}
+
+func TestBatch_RunsRouterMiddleware(t *testing.T) {
+	engine := gin.New()
+	group := engine.Group("/v1", func(c *gin.Context) {
+		if c.Request.Method == http.MethodDelete && c.GetHeader("X-Role") != "admin" {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	})
+	api := NewAPI(group, "/api")
+	api.EnableBatch("/batch")
+	res := &batchDeleteResource{}
+	api.AddResource("/notes", res)
+
+	if w := doRequest(engine, http.MethodDelete, "/v1/api/notes/1", ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected direct DELETE to get 403, got %d", w.Code)
+	}
+
+	w := doRequest(engine, http.MethodPost, "/v1/api/batch", `[{"method":"DELETE","path":"/v1/api/notes/1"}]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resps := decodeBatch(t, w.Body.Bytes())
+	if len(resps) != 1 || resps[0].Status != http.StatusForbidden {
+		t.Fatalf("expected batched DELETE to get 403, got %+v", resps)
+	}
+	if n := res.deleted.Load(); n != 0 {
+		t.Errorf("expected the handler not to run, ran %d times", n)
+	}
+}
+
+func TestEnableBatch_PanicsOnUnknownRouter(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected EnableBatch to panic")
+		}
+	}()
+	api := NewAPI(opaqueRouter{gin.New()}, "/api")
+	api.EnableBatch("/batch")
+}
+
+// opaqueRouter hides the concrete router type from the API.
+type opaqueRouter struct{ gin.IRouter }
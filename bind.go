@@ -1,9 +1,11 @@
 package restful
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // Bind binds the request body to T using gin's ShouldBind.
@@ -81,3 +83,13 @@ func MustBindURI[T any](c *gin.Context) *T {
 	}
 	return params
 }
+
+// BindItem decodes a single bulk item into T and validates it with gin's
+// validator, like Bind does for a whole request body.
+func BindItem[T any](item json.RawMessage) (*T, error) {
+	var body T
+	if err := binding.JSON.BindBody(item, &body); err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
@@ -0,0 +1,259 @@
+package restful
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultBulkMaxItems is the maximum number of items accepted by a bulk
+// operation unless WithBulkMaxItems is used.
+const DefaultBulkMaxItems = 1000
+
+// BulkResult reports the outcome of one item of a bulk operation. Set Err to
+// report a failed item; an *HTTPError provides its status and message, other
+// errors are reported as 500 without exposing their message.
+type BulkResult struct {
+	Status int
+	Body   any
+	Err    error
+}
+
+// BulkTransactor provides the transaction hook used by atomic bulk
+// operations (see WithBulkAtomic).
+type BulkTransactor interface {
+	BeginBulk(c *gin.Context) (BulkTx, error)
+}
+
+// BulkTx is a transaction opened by BulkTransactor.BeginBulk.
+type BulkTx interface {
+	Commit() error
+	Rollback() error
+}
+
+// WithBulkMaxItems limits the number of items accepted by the resource's bulk
+// operations. Larger batches are rejected with 413 Request Entity Too Large.
+func WithBulkMaxItems(n int) ResourceOption {
+	return func(cfg *resourceConfig) {
+		cfg.bulkMaxItems = n
+	}
+}
+
+// WithBulkAtomic makes the resource's bulk operations all-or-nothing. Each
+// operation runs inside a transaction opened by BulkTransactor.BeginBulk,
+// which the resource must implement. If the handler fails or panics or any
+// item fails, the transaction is rolled back and the request fails with the
+// status of the first failed item and the per-item results as details.
+func WithBulkAtomic() ResourceOption {
+	return func(cfg *resourceConfig) {
+		cfg.bulkAtomic = true
+	}
+}
+
+type bulkItemResult struct {
+	Index  int        `json:"index"`
+	Status int        `json:"status"`
+	Body   any        `json:"body,omitempty"`
+	Error  *HTTPError `json:"error,omitempty"`
+}
+
+type bulkResponse struct {
+	Results []bulkItemResult `json:"results"`
+}
+
+// bulkHandler adapts a bulk operation over decoded items to a handler.
+// txer is nil unless the resource is configured with WithBulkAtomic.
+func bulkHandler[T any](cfg resourceConfig, txer BulkTransactor, decode func(raw []json.RawMessage) ([]T, error), run func(items []T, c *gin.Context) ([]BulkResult, error)) func(c *gin.Context) (any, int, error) {
+	return func(c *gin.Context) (any, int, error) {
+		raw, err := readBulkBody(c, cfg.bulkMaxItems)
+		if err != nil {
+			return nil, 0, err
+		}
+		items, err := decode(raw)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var tx BulkTx
+		if txer != nil {
+			tx, err = txer.BeginBulk(c)
+			if err != nil {
+				return nil, 0, err
+			}
+			// A panicking handler must not leave the transaction open.
+			defer func() {
+				if p := recover(); p != nil {
+					_ = tx.Rollback()
+					panic(p)
+				}
+			}()
+		}
+
+		results, err := run(items, c)
+		if err == nil && len(results) != len(items) {
+			err = fmt.Errorf("gin-restful: bulk handler returned %d results for %d items", len(results), len(items))
+		}
+		if err != nil {
+			if tx != nil {
+				_ = tx.Rollback()
+			}
+			return nil, 0, err
+		}
+
+		resp := bulkResponse{Results: make([]bulkItemResult, len(results))}
+		failed := 0
+		for i, r := range results {
+			resp.Results[i] = newBulkItemResult(c, i, r)
+			if resp.Results[i].Error != nil && failed == 0 {
+				failed = resp.Results[i].Status
+			}
+		}
+
+		if tx == nil {
+			return resp, http.StatusMultiStatus, nil
+		}
+		if failed != 0 {
+			if err := tx.Rollback(); err != nil {
+				return nil, 0, err
+			}
+			return nil, 0, Abort(failed, "bulk operation rolled back",
+				WithCode("BULK_ROLLED_BACK"),
+				WithDetails(resp),
+			)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, 0, err
+		}
+		return resp, uniformStatus(resp.Results), nil
+	}
+}
+
+func newBulkItemResult(c *gin.Context, index int, r BulkResult) bulkItemResult {
+	item := bulkItemResult{Index: index, Status: r.Status, Body: r.Body}
+	if r.Err == nil {
+		if item.Status == 0 {
+			item.Status = http.StatusOK
+		}
+		return item
+	}
+
+	item.Body = nil
+	var httpErr *HTTPError
+	if errors.As(r.Err, &httpErr) {
+		item.Status = httpErr.Status
+		item.Error = httpErr
+		return item
+	}
+	_ = c.Error(r.Err)
+	if item.Status == 0 {
+		item.Status = http.StatusInternalServerError
+	}
+	item.Error = &HTTPError{Status: item.Status, Message: "internal server error"}
+	return item
+}
+
+// uniformStatus returns the status shared by all results, or 200 OK.
+func uniformStatus(results []bulkItemResult) int {
+	if len(results) == 0 {
+		return http.StatusOK
+	}
+	status := results[0].Status
+	for _, r := range results[1:] {
+		if r.Status != status {
+			return http.StatusOK
+		}
+	}
+	return status
+}
+
+// readBulkBody decodes the items of a bulk request body, one by one so that
+// bodies with more than maxItems items are rejected without parsing them
+// whole.
+func readBulkBody(c *gin.Context, maxItems int) ([]json.RawMessage, error) {
+	if maxItems <= 0 {
+		maxItems = DefaultBulkMaxItems
+	}
+	malformed := Abort(http.StatusBadRequest, "bulk request body must be a JSON array")
+	if c.Request.Body == nil {
+		return nil, malformed
+	}
+	dec := json.NewDecoder(c.Request.Body)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, malformed
+	}
+	var items []json.RawMessage
+	for dec.More() {
+		if len(items) == maxItems {
+			return nil, Abort(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("bulk request contains more than %d items", maxItems),
+				WithCode("BULK_TOO_LARGE"),
+			)
+		}
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return nil, malformed
+		}
+		items = append(items, item)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, malformed
+	}
+	if len(items) == 0 {
+		return nil, Abort(http.StatusBadRequest, "bulk request contains no items")
+	}
+	return items, nil
+}
+
+func decodeRawItems(raw []json.RawMessage) ([]json.RawMessage, error) {
+	return raw, nil
+}
+
+func decodeBulkIDs(raw []json.RawMessage) ([]string, error) {
+	ids := make([]string, len(raw))
+	for i, item := range raw {
+		var id any
+		if err := json.Unmarshal(item, &id); err != nil {
+			return nil, Abort(http.StatusBadRequest, "bulk delete body must be a JSON array of ids")
+		}
+		switch v := id.(type) {
+		case string:
+			ids[i] = v
+		case float64:
+			ids[i] = string(bytes.TrimSpace(item))
+		default:
+			return nil, Abort(http.StatusBadRequest, "bulk delete body must be a JSON array of ids")
+		}
+	}
+	return ids, nil
+}
+
+// isJSONArrayBody reports whether the request body starts with a JSON array,
+// leaving the body readable for the handler.
+func isJSONArrayBody(c *gin.Context) bool {
+	if c.Request.Body == nil {
+		return false
+	}
+	body := bufio.NewReader(c.Request.Body)
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, c.Request.Body}
+	for {
+		b, err := body.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		_ = body.UnreadByte()
+		return b == '['
+	}
+}
@@ -0,0 +1,275 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type bulkResource struct {
+	committed  bool
+	rolledBack bool
+}
+
+func (r *bulkResource) Post(c *gin.Context) (any, int, error) {
+	return gin.H{"single": true}, http.StatusCreated, nil
+}
+
+func (r *bulkResource) BulkPost(items []json.RawMessage, c *gin.Context) ([]BulkResult, error) {
+	results := make([]BulkResult, len(items))
+	for i, item := range items {
+		body, err := BindItem[testBody](item)
+		if err != nil {
+			results[i] = BulkResult{Err: Abort(http.StatusBadRequest, err.Error())}
+			continue
+		}
+		results[i] = BulkResult{Status: http.StatusCreated, Body: gin.H{"name": body.Name}}
+	}
+	return results, nil
+}
+
+func (r *bulkResource) BulkPatch(items []json.RawMessage, c *gin.Context) ([]BulkResult, error) {
+	return nil, Abort(http.StatusServiceUnavailable, "storage offline")
+}
+
+func (r *bulkResource) BulkDelete(ids []string, c *gin.Context) ([]BulkResult, error) {
+	results := make([]BulkResult, len(ids))
+	for i, id := range ids {
+		if id == "missing" {
+			results[i] = BulkResult{Err: Abort(http.StatusNotFound, "not found")}
+			continue
+		}
+		results[i] = BulkResult{Status: http.StatusNoContent}
+	}
+	return results, nil
+}
+
+func (r *bulkResource) BeginBulk(c *gin.Context) (BulkTx, error) {
+	return r, nil
+}
+
+func (r *bulkResource) Commit() error {
+	r.committed = true
+	return nil
+}
+
+func (r *bulkResource) Rollback() error {
+	r.rolledBack = true
+	return nil
+}
+
+type bulkOnlyResource struct{}
+
+func (r *bulkOnlyResource) BulkPost(items []json.RawMessage, c *gin.Context) ([]BulkResult, error) {
+	return make([]BulkResult, len(items)-1), nil // wrong number of results
+}
+
+// panickingBulkResource panics while its transaction is open.
+type panickingBulkResource struct {
+	bulkResource
+}
+
+func (r *panickingBulkResource) BulkPost(items []json.RawMessage, c *gin.Context) ([]BulkResult, error) {
+	panic("storage driver bug")
+}
+
+// --- helpers ---
+
+func setupBulkRouter(resource any, opts ...ResourceOption) *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/items", resource, opts...)
+	return engine
+}
+
+func decodeBulk(t *testing.T, body []byte) []bulkItemResult {
+	t.Helper()
+	var resp struct {
+		Results []bulkItemResult `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return resp.Results
+}
+
+// --- tests ---
+
+func TestBulkPost_PerItemResults(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{})
+
+	w := doRequest(engine, "POST", "/api/items", `[{"name":"a"},{"age":3},{"name":"c"}]`)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d", w.Code)
+	}
+	results := decodeBulk(t, w.Body.Bytes())
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Status != http.StatusCreated || results[2].Status != http.StatusCreated {
+		t.Errorf("expected created items, got %+v", results)
+	}
+	if results[1].Status != http.StatusBadRequest || results[1].Error == nil || results[1].Index != 1 {
+		t.Errorf("expected item 1 to fail validation, got %+v", results[1])
+	}
+}
+
+func TestBulkPost_ObjectBodyRoutesToPost(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{})
+
+	w := doRequest(engine, "POST", "/api/items", `{"name":"a"}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), "single") {
+		t.Errorf("expected single Post, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBulkPost_MaxItems_Returns413(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{}, WithBulkMaxItems(2))
+
+	w := doRequest(engine, "POST", "/api/items", `[{"name":"a"},{"name":"b"},{"name":"c"}]`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestBulkPost_MaxItems_StopsReading(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{}, WithBulkMaxItems(2))
+
+	// The malformed tail is past the limit, so it is never parsed.
+	w := doRequest(engine, "POST", "/api/items", `[{"name":"a"},{"name":"b"},{"name":"c"},`+strings.Repeat("x", 1<<20))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestBulkPost_MalformedArray_Returns400(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{})
+
+	for _, body := range []string{`[{"name":"a"}`, `[{"name":"a"} {"name":"b"}]`, ` [{"name":`} {
+		if w := doRequest(engine, "POST", "/api/items", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestBulkPost_EmptyArray_Returns400(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{})
+
+	w := doRequest(engine, "POST", "/api/items", `[]`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestBulkPost_ResultCountMismatch_Returns500(t *testing.T) {
+	engine := setupBulkRouter(&bulkOnlyResource{})
+
+	w := doRequest(engine, "POST", "/api/items", `[{},{}]`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
+
+func TestBulkPatch_HandlerError(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{})
+
+	w := doRequest(engine, "PATCH", "/api/items", `[{"id":"1"}]`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", w.Code)
+	}
+}
+
+func TestBulkDelete_IDs(t *testing.T) {
+	engine := setupBulkRouter(&bulkResource{})
+
+	w := doRequest(engine, "DELETE", "/api/items", `["1", 2, "missing"]`)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d", w.Code)
+	}
+	results := decodeBulk(t, w.Body.Bytes())
+	if results[1].Status != http.StatusNoContent || results[2].Status != http.StatusNotFound {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	w = doRequest(engine, "DELETE", "/api/items", `[{"id":1}]`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for non-id items, got %d", w.Code)
+	}
+}
+
+func TestBulkAtomic_CommitsOnSuccess(t *testing.T) {
+	resource := &bulkResource{}
+	engine := setupBulkRouter(resource, WithBulkAtomic())
+
+	w := doRequest(engine, "POST", "/api/items", `[{"name":"a"},{"name":"b"}]`)
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", w.Code)
+	}
+	if !resource.committed || resource.rolledBack {
+		t.Errorf("expected commit, got committed=%v rolledBack=%v", resource.committed, resource.rolledBack)
+	}
+}
+
+func TestBulkAtomic_RollsBackOnItemFailure(t *testing.T) {
+	resource := &bulkResource{}
+	engine := setupBulkRouter(resource, WithBulkAtomic())
+
+	w := doRequest(engine, "DELETE", "/api/items", `["1","missing"]`)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	if resource.committed || !resource.rolledBack {
+		t.Errorf("expected rollback, got committed=%v rolledBack=%v", resource.committed, resource.rolledBack)
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp["code"] != "BULK_ROLLED_BACK" || resp["details"] == nil {
+		t.Errorf("expected rolled back error with details, got %v", resp)
+	}
+}
+
+func TestBulkAtomic_RollsBackOnPanic(t *testing.T) {
+	resource := &panickingBulkResource{}
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRecovery())
+	api.AddResource("/items", resource, WithBulkAtomic())
+
+	w := doRequest(engine, "POST", "/api/items", `[{"name":"a"}]`)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if resource.committed || !resource.rolledBack {
+		t.Errorf("expected rollback, got committed=%v rolledBack=%v", resource.committed, resource.rolledBack)
+	}
+}
+
+func TestBulkAtomic_WithoutTransactor_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for WithBulkAtomic without BulkTransactor")
+		}
+	}()
+	setupBulkRouter(&bulkOnlyResource{}, WithBulkAtomic())
+}
+
+func TestBindItem(t *testing.T) {
+	body, err := BindItem[testBody](json.RawMessage(`{"name":"alice","age":3}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.Name != "alice" || body.Age != 3 {
+		t.Errorf("unexpected body: %+v", body)
+	}
+
+	if _, err := BindItem[testBody](json.RawMessage(`{"age":3}`)); err == nil {
+		t.Error("expected validation error for missing name")
+	}
+}
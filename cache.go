@@ -0,0 +1,443 @@
+package restful
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultCacheTTL is how long responses are cached when neither
+	// WithCacheTTL nor their Cache-Control header says otherwise.
+	DefaultCacheTTL = time.Minute
+	// DefaultCacheCapacity is the number of responses kept by the in-memory
+	// store used unless WithCacheStore is given.
+	DefaultCacheCapacity = 10000
+)
+
+// CachedResponse is a response stored by WithResponseCache.
+type CachedResponse struct {
+	// Status is zero for the placeholder stored under the key of a request
+	// when the responses to it vary by request header or by principal; the
+	// responses themselves are stored under keys extended with the values
+	// they vary by.
+	Status int
+	Header http.Header
+	Body   []byte
+	// Vary lists the request headers the response varies by, from its
+	// Vary header.
+	Vary []string
+	// Private is set when the response is specific to the principal, i.e.
+	// not marked "Cache-Control: public".
+	Private bool
+	// Tags are the invalidation tags of the response, see CacheStore.
+	Tags   []string
+	Stored time.Time
+}
+
+// CacheStore keeps the responses cached by WithResponseCache. Keys passed
+// to the store are already scoped to the route and request. Every response
+// carries tags naming the resource, its collection or the item it reads,
+// and Invalidate drops all the responses having any of the given tags.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the response stored under key, or nil.
+	Get(ctx context.Context, key string) (*CachedResponse, error)
+	// Set stores resp under key for ttl.
+	Set(ctx context.Context, key string, resp CachedResponse, ttl time.Duration) error
+	// Invalidate drops the responses with any of tags.
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// CacheOption configures WithResponseCache.
+type CacheOption func(*cacheConfig)
+
+type cacheConfig struct {
+	store CacheStore
+	ttl   time.Duration
+}
+
+// WithCacheStore sets the store for cached responses, e.g. one shared by
+// several resources. The default is an in-memory MemoryCacheStore.
+func WithCacheStore(store CacheStore) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.store = store
+	}
+}
+
+// WithCacheTTL sets how long responses are cached when their
+// Cache-Control header has no max-age or s-maxage directive.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithResponseCache caches the rendered 200 responses of the resource's
+// Get and List routes, keyed by route, path parameters and query
+// parameters in any order, and replays them with an Age header while they
+// are fresh. The Cache-Control header of responses is honored: no-store and
+// no-cache responses are not cached, max-age and s-maxage set their TTL,
+// and responses are cached per principal set with SetPrincipal unless they
+// are public, which makes them shared by every caller. List responses of
+// resources implementing RowFilter are never shared. The resource's Policy
+// is still enforced before a cached response is replayed, so a response
+// cached for one caller never reaches a caller the policy denies. Responses
+// also vary by the request headers their Vary header names. Requests with
+// "Cache-Control: no-cache" bypass the cache, and with no-store are not
+// cached either. Successful writes to the resource invalidate its cached
+// responses: those of the item and of the collection for writes to an item,
+// and all of them otherwise.
+func WithResponseCache(opts ...CacheOption) ResourceOption {
+	cfg := &cacheConfig{ttl: DefaultCacheTTL}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryCacheStore(DefaultCacheCapacity)
+	}
+	return func(rc *resourceConfig) {
+		rc.cache = cfg
+	}
+}
+
+// responseCache caches the responses of one resource.
+type responseCache struct {
+	cfg      *cacheConfig
+	resource string
+	// filtered is set when the resource filters the rows of its List
+	// responses by principal, which must then not be shared.
+	filtered bool
+	// generation counts invalidations, so that responses computed while a
+	// write was running are not stored.
+	generation atomic.Uint64
+}
+
+func newResponseCache(cfg *cacheConfig, resource string, filtered bool) *responseCache {
+	return &responseCache{cfg: cfg, resource: resource, filtered: filtered}
+}
+
+// Invalidation tags of a resource's responses.
+func (rc *responseCache) allTag() string  { return rc.resource }
+func (rc *responseCache) listTag() string { return rc.resource + "#list" }
+func (rc *responseCache) itemTag(id string) string {
+	return rc.resource + "#item=" + id
+}
+
+// handler returns the middleware of a route of the resource: serving from
+// the cache for Get and List, invalidating it for writes. authorize, if not
+// nil, is the policy check of the route's operation.
+func (rc *responseCache) handler(method string, names []string, item bool, authorize func(c *gin.Context) error) gin.HandlerFunc {
+	switch {
+	case method == http.MethodGet && slices.Contains(names, "List"):
+		return rc.serve(authorize, !rc.filtered, func(*gin.Context) []string {
+			return []string{rc.allTag(), rc.listTag()}
+		})
+	case method == http.MethodGet && slices.Contains(names, "Get") && item:
+		return rc.serve(authorize, true, func(c *gin.Context) []string {
+			return []string{rc.allTag(), rc.itemTag(c.Param("id"))}
+		})
+	case method == http.MethodGet && slices.Contains(names, "Get"):
+		return rc.serve(authorize, true, func(*gin.Context) []string {
+			return []string{rc.allTag()}
+		})
+	case method == http.MethodGet || method == http.MethodHead:
+		return nil
+	}
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		if _, ok := responseErrorFrom(c); ok {
+			return
+		}
+		tags := []string{rc.allTag()}
+		if item {
+			tags = []string{rc.itemTag(c.Param("id")), rc.listTag()}
+		}
+		rc.generation.Add(1)
+		if err := rc.cfg.store.Invalidate(context.WithoutCancel(c.Request.Context()), tags...); err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+// serve is the middleware replaying cached responses and caching new ones.
+// Cached responses are only replayed to requests passing authorize, and
+// only shared between principals when shareable is set. Other requests
+// are authorized by the handler.
+func (rc *responseCache) serve(authorize func(c *gin.Context) error, shareable bool, tags func(c *gin.Context) []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		directives := parseCacheControl(c.Request.Header.Values("Cache-Control"))
+		if _, ok := directives["no-store"]; ok {
+			c.Next()
+			return
+		}
+		key := routeKey(c)
+		if _, ok := directives["no-cache"]; !ok && directives["max-age"] != "0" {
+			if resp := rc.lookup(c, key); resp != nil {
+				if authorize != nil {
+					if err := authorize(c); err != nil {
+						RenderError(c, err)
+						return
+					}
+				}
+				replayCached(c, resp)
+				return
+			}
+		}
+
+		generation := rc.generation.Load()
+		capture := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = capture
+		defer func() {
+			c.Writer = capture.ResponseWriter
+		}()
+		c.Next()
+
+		if rc.generation.Load() == generation {
+			rc.store(c, key, capture, shareable, tags(c))
+		}
+	}
+}
+
+// lookup returns the fresh response cached for the request, or nil.
+func (rc *responseCache) lookup(c *gin.Context, key string) *CachedResponse {
+	ctx := c.Request.Context()
+	resp, err := rc.cfg.store.Get(ctx, key)
+	if err == nil && resp != nil && resp.Status == 0 {
+		resp, err = rc.cfg.store.Get(ctx, variantKey(c, key, resp))
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return nil
+	}
+	if resp == nil || resp.Status == 0 {
+		return nil
+	}
+	return resp
+}
+
+// store caches the response captured by w if it may be.
+func (rc *responseCache) store(c *gin.Context, key string, w *captureWriter, shareable bool, tags []string) {
+	if w.Status() != http.StatusOK || w.streamed {
+		return
+	}
+	if _, ok := responseErrorFrom(c); ok {
+		return
+	}
+	header := w.Header()
+	if header.Get("Set-Cookie") != "" {
+		return
+	}
+	directives := parseCacheControl(header.Values("Cache-Control"))
+	_, noStore := directives["no-store"]
+	_, noCache := directives["no-cache"]
+	if noStore || noCache {
+		return
+	}
+	ttl := rc.cfg.ttl
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := directives[directive]; ok {
+			seconds, err := strconv.Atoi(v)
+			if err != nil {
+				return
+			}
+			ttl = time.Duration(seconds) * time.Second
+			break
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+	var vary []string
+	for _, value := range header.Values("Vary") {
+		for name := range strings.SplitSeq(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return
+			} else if name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	_, public := directives["public"]
+	public = public && shareable
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	resp := CachedResponse{
+		Status:  http.StatusOK,
+		Header:  withoutPerRequestHeaders(header),
+		Body:    w.body.Bytes(),
+		Vary:    vary,
+		Private: !public,
+		Tags:    tags,
+		Stored:  time.Now(),
+	}
+	// The age belongs to the response being served.
+	resp.Header.Del("Age")
+	if len(vary) > 0 || resp.Private {
+		placeholder := CachedResponse{Vary: vary, Private: resp.Private, Tags: tags, Stored: resp.Stored}
+		if err := rc.cfg.store.Set(ctx, key, placeholder, ttl); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		key = variantKey(c, key, &resp)
+	}
+	if err := rc.cfg.store.Set(ctx, key, resp, ttl); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// variantKey extends key with the values the responses to the request
+// vary by.
+func variantKey(c *gin.Context, key string, resp *CachedResponse) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range resp.Vary {
+		b.WriteString("\x00" + name + "=" + strings.Join(c.Request.Header.Values(name), ","))
+	}
+	if resp.Private {
+		b.WriteString("\x00principal=")
+		if p, ok := PrincipalFrom(c); ok {
+			b.WriteString(p.Subject)
+		}
+	}
+	return b.String()
+}
+
+func replayCached(c *gin.Context, resp *CachedResponse) {
+	for name, values := range resp.Header {
+		c.Writer.Header()[name] = slices.Clone(values)
+	}
+	c.Header("Age", strconv.Itoa(int(time.Since(resp.Stored).Seconds())))
+	c.Status(resp.Status)
+	_, _ = c.Writer.Write(resp.Body)
+	c.Abort()
+}
+
+// parseCacheControl returns the directives of Cache-Control header values,
+// with lowercase names and unquoted arguments.
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range values {
+		for directive := range strings.SplitSeq(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// MemoryCacheStore is an in-memory CacheStore that evicts the least
+// recently used responses beyond its capacity, as well as expired ones.
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // of *memoryCacheEntry, most recently used first
+	entries  map[string]*list.Element
+	tags     map[string]map[*list.Element]struct{}
+	now      func() time.Time
+}
+
+type memoryCacheEntry struct {
+	key     string
+	resp    CachedResponse
+	expires time.Time
+}
+
+// NewMemoryCacheStore returns a MemoryCacheStore holding up to capacity
+// responses, or any number of responses when capacity is zero.
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		tags:     make(map[string]map[*list.Element]struct{}),
+		now:      time.Now,
+	}
+}
+
+// Get implements CacheStore.
+func (s *MemoryCacheStore) Get(_ context.Context, key string) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if !s.now().Before(entry.expires) {
+		s.remove(elem)
+		return nil, nil
+	}
+	s.order.MoveToFront(elem)
+	resp := entry.resp
+	return &resp, nil
+}
+
+// Set implements CacheStore.
+func (s *MemoryCacheStore) Set(_ context.Context, key string, resp CachedResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+	elem := s.order.PushFront(&memoryCacheEntry{key: key, resp: resp, expires: s.now().Add(ttl)})
+	s.entries[key] = elem
+	for _, tag := range resp.Tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[*list.Element]struct{})
+		}
+		s.tags[tag][elem] = struct{}{}
+	}
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Invalidate implements CacheStore.
+func (s *MemoryCacheStore) Invalidate(_ context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		for elem := range s.tags[tag] {
+			s.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of responses held, including expired ones not
+// evicted yet.
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *MemoryCacheStore) remove(elem *list.Element) {
+	entry := elem.Value.(*memoryCacheEntry)
+	s.order.Remove(elem)
+	delete(s.entries, entry.key)
+	for _, tag := range entry.resp.Tags {
+		delete(s.tags[tag], elem)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
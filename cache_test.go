@@ -0,0 +1,400 @@
+package restful
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+// pricedResource counts its reads. The cc and vary query parameters set
+// the Cache-Control and Vary headers of responses.
+type pricedResource struct {
+	reads atomic.Int32
+}
+
+func (r *pricedResource) headers(c *gin.Context) {
+	if cc := c.Query("cc"); cc != "" {
+		c.Header("Cache-Control", cc)
+	}
+	if vary := c.Query("vary"); vary != "" {
+		c.Header("Vary", vary)
+	}
+}
+
+func (r *pricedResource) List(c *gin.Context) (any, int, error) {
+	n := r.reads.Add(1)
+	r.headers(c)
+	return []gin.H{{"id": "1", "read": n}}, http.StatusOK, nil
+}
+
+func (r *pricedResource) Get(id string, c *gin.Context) (any, int, error) {
+	n := r.reads.Add(1)
+	if id == "missing" {
+		return nil, 0, Abort(http.StatusNotFound, "not found")
+	}
+	r.headers(c)
+	return gin.H{"id": id, "read": n, "lang": c.GetHeader("Accept-Language")}, http.StatusOK, nil
+}
+
+func (r *pricedResource) Post(c *gin.Context) (any, int, error) {
+	return gin.H{"id": "3"}, http.StatusCreated, nil
+}
+
+func (r *pricedResource) Put(id string, c *gin.Context) (any, int, error) {
+	if id == "missing" {
+		return nil, 0, Abort(http.StatusNotFound, "not found")
+	}
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+// preferencesResource is a singleton counting its reads.
+type preferencesResource struct {
+	reads atomic.Int32
+}
+
+func (r *preferencesResource) Get(c *gin.Context) (any, int, error) {
+	return gin.H{"read": r.reads.Add(1)}, http.StatusOK, nil
+}
+
+func (r *preferencesResource) Put(c *gin.Context) (any, int, error) {
+	return gin.H{}, http.StatusOK, nil
+}
+
+// securedReportResource lets admins list its reports, which it marks as
+// public, and filters them by owner.
+type securedReportResource struct {
+	reads atomic.Int32
+}
+
+func (r *securedReportResource) Policy() Policy {
+	return Policy{List: &Requirement{Roles: []string{"admin"}}, Get: &Requirement{Public: true}}
+}
+
+func (r *securedReportResource) CachePolicy() CachePolicy {
+	return CachePolicy{Default: &CacheControl{Public: true, MaxAge: time.Minute}}
+}
+
+func (r *securedReportResource) List(c *gin.Context) (any, int, error) {
+	r.reads.Add(1)
+	return []gin.H{{"owner": "alice", "title": "secret"}, {"owner": "bob", "title": "draft"}}, http.StatusOK, nil
+}
+
+func (r *securedReportResource) FilterRow(p *Principal, item any, c *gin.Context) bool {
+	return p != nil && item.(gin.H)["owner"] == p.Subject
+}
+
+// --- helpers ---
+
+func setupCacheRouter(resource any, opts ...CacheOption) *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRequestID(nil))
+	api.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			SetPrincipal(c, &Principal{Subject: user})
+		}
+	})
+	api.AddResource("/products", resource, WithResponseCache(opts...))
+	return engine
+}
+
+// expectRead checks the body of a read made after n reads of the resource.
+func expectRead(t *testing.T, body string, n int) {
+	t.Helper()
+	if !strings.Contains(body, fmt.Sprintf(`"read":%d`, n)) {
+		t.Errorf("expected read %d, got %s", n, body)
+	}
+}
+
+// --- tests ---
+
+func TestResponseCache_ReplaysGet(t *testing.T) {
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource)
+
+	first := doRequest(engine, "GET", "/api/products/1", "")
+	second := doRequest(engine, "GET", "/api/products/1", "")
+
+	if resource.reads.Load() != 1 {
+		t.Errorf("expected a single read, got %d", resource.reads.Load())
+	}
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Errorf("expected the cached response, got %d %s", second.Code, second.Body.String())
+	}
+	if second.Header().Get("Age") != "0" || first.Header().Get("Age") != "" {
+		t.Errorf("expected Age on the cached response only, got %q and %q", first.Header().Get("Age"), second.Header().Get("Age"))
+	}
+	if second.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("expected the cached headers, got %v", second.Header())
+	}
+	if id := second.Header().Get(RequestIDHeader); id == "" || id == first.Header().Get(RequestIDHeader) {
+		t.Errorf("expected a new request ID, got %q", id)
+	}
+}
+
+func TestResponseCache_Keys(t *testing.T) {
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource)
+
+	expectRead(t, doRequest(engine, "GET", "/api/products?sort=name&page=2", "").Body.String(), 1)
+	expectRead(t, doRequest(engine, "GET", "/api/products?page=2&sort=name", "").Body.String(), 1)
+	expectRead(t, doRequest(engine, "GET", "/api/products?page=3&sort=name", "").Body.String(), 2)
+	expectRead(t, doRequest(engine, "GET", "/api/products/1", "").Body.String(), 3)
+	expectRead(t, doRequest(engine, "GET", "/api/products/2", "").Body.String(), 4)
+	expectRead(t, doRequest(engine, "GET", "/api/products/1", "").Body.String(), 3)
+}
+
+func TestResponseCache_InvalidatesOnWrites(t *testing.T) {
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource)
+
+	doRequest(engine, "GET", "/api/products", "")   // read 1
+	doRequest(engine, "GET", "/api/products/1", "") // read 2
+	doRequest(engine, "GET", "/api/products/2", "") // read 3
+
+	// A write to an item invalidates it and the collection.
+	if w := doRequest(engine, "PUT", "/api/products/1", `{}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	expectRead(t, doRequest(engine, "GET", "/api/products/1", "").Body.String(), 4)
+	expectRead(t, doRequest(engine, "GET", "/api/products", "").Body.String(), 5)
+	expectRead(t, doRequest(engine, "GET", "/api/products/2", "").Body.String(), 3)
+
+	// Failed writes do not.
+	doRequest(engine, "PUT", "/api/products/missing", `{}`)
+	expectRead(t, doRequest(engine, "GET", "/api/products", "").Body.String(), 5)
+
+	// A write to the collection invalidates everything.
+	if w := doRequest(engine, "POST", "/api/products", `{}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	expectRead(t, doRequest(engine, "GET", "/api/products/2", "").Body.String(), 6)
+	expectRead(t, doRequest(engine, "GET", "/api/products", "").Body.String(), 7)
+}
+
+func TestResponseCache_Singleton(t *testing.T) {
+	resource := &preferencesResource{}
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddSingleton("/settings", resource, WithResponseCache())
+
+	expectRead(t, doRequest(engine, "GET", "/api/settings", "").Body.String(), 1)
+	expectRead(t, doRequest(engine, "GET", "/api/settings", "").Body.String(), 1)
+	doRequest(engine, "PUT", "/api/settings", `{}`)
+	expectRead(t, doRequest(engine, "GET", "/api/settings", "").Body.String(), 2)
+}
+
+func TestResponseCache_SkipsErrors(t *testing.T) {
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource)
+
+	doRequest(engine, "GET", "/api/products/missing", "")
+	w := doRequest(engine, "GET", "/api/products/missing", "")
+	if w.Code != http.StatusNotFound || resource.reads.Load() != 2 {
+		t.Errorf("expected errors not to be cached, got %d after %d reads", w.Code, resource.reads.Load())
+	}
+}
+
+func TestResponseCache_ResponseCacheControl(t *testing.T) {
+	tests := []struct {
+		name   string
+		cc     string
+		cached bool
+	}{
+		{"none", "", true},
+		{"max-age", "max-age=60", true},
+		{"no-store", "no-store", false},
+		{"no-cache", "no-cache", false},
+		{"max-age=0", "max-age=0", false},
+		{"s-maxage=0", "public, s-maxage=0, max-age=60", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &pricedResource{}
+			engine := setupCacheRouter(resource)
+
+			path := "/api/products/1?cc=" + strings.ReplaceAll(tt.cc, " ", "+")
+			doRequest(engine, "GET", path, "")
+			w := doRequest(engine, "GET", path, "")
+			if want := map[bool]int{true: 1, false: 2}[tt.cached]; int(resource.reads.Load()) != want {
+				t.Errorf("expected %d reads, got %d", want, resource.reads.Load())
+			}
+			if w.Header().Get("Cache-Control") != tt.cc {
+				t.Errorf("expected Cache-Control %q, got %q", tt.cc, w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestResponseCache_TTL(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryCacheStore(0)
+	store.now = func() time.Time { return now }
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource, WithCacheStore(store), WithCacheTTL(time.Minute))
+
+	doRequest(engine, "GET", "/api/products/1", "")
+	doRequest(engine, "GET", "/api/products/1?cc=max-age%3D600", "")
+	now = now.Add(2 * time.Minute)
+
+	expectRead(t, doRequest(engine, "GET", "/api/products/1", "").Body.String(), 3)
+	expectRead(t, doRequest(engine, "GET", "/api/products/1?cc=max-age%3D600", "").Body.String(), 2)
+}
+
+func TestResponseCache_Principals(t *testing.T) {
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource)
+	alice := map[string]string{"X-User": "alice"}
+	bob := map[string]string{"X-User": "bob"}
+
+	// Responses are private by default.
+	expectRead(t, doRequestWithHeaders(engine, "GET", "/api/products/1", alice).Body.String(), 1)
+	expectRead(t, doRequestWithHeaders(engine, "GET", "/api/products/1", bob).Body.String(), 2)
+	expectRead(t, doRequestWithHeaders(engine, "GET", "/api/products/1", alice).Body.String(), 1)
+
+	// Public ones are shared.
+	expectRead(t, doRequestWithHeaders(engine, "GET", "/api/products/2?cc=public", alice).Body.String(), 3)
+	expectRead(t, doRequestWithHeaders(engine, "GET", "/api/products/2?cc=public", bob).Body.String(), 3)
+}
+
+func TestResponseCache_EnforcesPolicy(t *testing.T) {
+	resource := &securedReportResource{}
+	var authorized []string
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithAuthorizer(AuthorizerFunc(func(p *Principal, op Operation, req Requirement, c *gin.Context) error {
+		authorized = append(authorized, op.Name)
+		return authorizeRequirement(p, op, req, c)
+	})))
+	api.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			SetPrincipal(c, &Principal{Subject: user, Roles: []string{c.GetHeader("X-Role")}})
+		}
+	})
+	api.AddResource("/reports", resource, WithResponseCache())
+	admin := map[string]string{"X-User": "alice", "X-Role": "admin"}
+
+	if w := doRequestWithHeaders(engine, "GET", "/api/reports", admin); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w := doRequest(engine, "GET", "/api/reports", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an anonymous caller, got %d %s", w.Code, w.Body.String())
+	}
+	user := map[string]string{"X-User": "bob", "X-Role": "user"}
+	if w := doRequestWithHeaders(engine, "GET", "/api/reports", user); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a user, got %d %s", w.Code, w.Body.String())
+	}
+	w := doRequestWithHeaders(engine, "GET", "/api/reports", admin)
+	if w.Code != http.StatusOK || w.Header().Get("Age") == "" || resource.reads.Load() != 1 {
+		t.Errorf("expected the cached response for the admin, got %d after %d reads", w.Code, resource.reads.Load())
+	}
+	// The anonymous caller is refused before the Authorizer is consulted.
+	if len(authorized) != 3 || authorized[2] != "List" {
+		t.Errorf("expected every authenticated request to be authorized once, got %v", authorized)
+	}
+}
+
+func TestResponseCache_RowFilteredListsAreNotShared(t *testing.T) {
+	resource := &securedReportResource{}
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.Use(func(c *gin.Context) {
+		SetPrincipal(c, &Principal{Subject: c.GetHeader("X-User"), Roles: []string{"admin"}})
+	})
+	api.AddResource("/reports", resource, WithResponseCache())
+
+	alice := doRequestWithHeaders(engine, "GET", "/api/reports", map[string]string{"X-User": "alice"})
+	bob := doRequestWithHeaders(engine, "GET", "/api/reports", map[string]string{"X-User": "bob"})
+	if !strings.Contains(alice.Body.String(), "secret") || strings.Contains(bob.Body.String(), "secret") {
+		t.Errorf("expected each caller to see their own reports, got %s and %s", alice.Body.String(), bob.Body.String())
+	}
+}
+
+func TestResponseCache_KeepsPerRequestHeaders(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/products", &pricedResource{},
+		WithResponseCache(), WithRateLimit(RateLimit{Limit: 10, Window: time.Minute}))
+
+	doRequest(engine, "GET", "/api/products/1", "")
+	w := doRequest(engine, "GET", "/api/products/1", "")
+	if w.Header().Get("Age") == "" || w.Header().Get("RateLimit-Remaining") != "8" {
+		t.Errorf("expected the cached response with the current rate limit, got %v", w.Header())
+	}
+}
+
+func TestResponseCache_Vary(t *testing.T) {
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource)
+	path := "/api/products/1?cc=public&vary=accept-language"
+
+	en := doRequestWithHeaders(engine, "GET", path, map[string]string{"Accept-Language": "en"})
+	ko := doRequestWithHeaders(engine, "GET", path, map[string]string{"Accept-Language": "ko"})
+	again := doRequestWithHeaders(engine, "GET", path, map[string]string{"Accept-Language": "en"})
+
+	expectRead(t, en.Body.String(), 1)
+	expectRead(t, ko.Body.String(), 2)
+	if again.Body.String() != en.Body.String() || again.Header().Get("Age") == "" {
+		t.Errorf("expected the cached English response, got %s", again.Body.String())
+	}
+
+	doRequest(engine, "GET", "/api/products/2?vary=*", "")
+	expectRead(t, doRequest(engine, "GET", "/api/products/2?vary=*", "").Body.String(), 4)
+}
+
+func TestResponseCache_RequestCacheControl(t *testing.T) {
+	resource := &pricedResource{}
+	engine := setupCacheRouter(resource)
+
+	doRequest(engine, "GET", "/api/products/1", "")
+	// no-cache skips the cached response but refreshes it.
+	expectRead(t, doRequestWithHeaders(engine, "GET", "/api/products/1", map[string]string{"Cache-Control": "no-cache"}).Body.String(), 2)
+	expectRead(t, doRequest(engine, "GET", "/api/products/1", "").Body.String(), 2)
+	// no-store neither reads nor stores.
+	expectRead(t, doRequestWithHeaders(engine, "GET", "/api/products/2", map[string]string{"Cache-Control": "no-store"}).Body.String(), 3)
+	expectRead(t, doRequest(engine, "GET", "/api/products/2", "").Body.String(), 4)
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCacheStore(2)
+	set := func(key string, tags ...string) {
+		if err := store.Set(ctx, key, CachedResponse{Status: http.StatusOK, Tags: tags}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	has := func(key string) bool {
+		resp, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp != nil
+	}
+
+	set("a", "all", "item=a")
+	set("b", "all", "item=b")
+	has("a")
+	set("c", "all", "item=c")
+	if !has("a") || has("b") || !has("c") {
+		t.Errorf("expected the least recently used response to be evicted")
+	}
+
+	if err := store.Invalidate(ctx, "item=a"); err != nil {
+		t.Fatal(err)
+	}
+	if has("a") || !has("c") {
+		t.Errorf("expected only the tagged response to be invalidated")
+	}
+	if err := store.Invalidate(ctx, "all"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 0 || len(store.tags) != 0 {
+		t.Errorf("expected an empty store, got %d responses and %d tags", store.Len(), len(store.tags))
+	}
+}
@@ -0,0 +1,166 @@
+package restful
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControl is the HTTP caching policy of an operation's responses,
+// sent as their Cache-Control and Vary headers. Durations are rounded up to
+// whole seconds, and zero durations are left out.
+type CacheControl struct {
+	// Public lets shared caches store responses, even to authenticated
+	// requests. Private restricts them to the client's cache.
+	Public  bool
+	Private bool
+	// NoStore forbids caching responses. The other directives are then
+	// left out.
+	NoStore bool
+	// MaxAge is how long responses stay fresh.
+	MaxAge time.Duration
+	// SMaxAge is how long responses stay fresh in shared caches, overriding
+	// MaxAge there.
+	SMaxAge time.Duration
+	// StaleWhileRevalidate is how long stale responses may still be served
+	// while caches fetch a fresh one in the background.
+	StaleWhileRevalidate time.Duration
+	// Vary lists the request headers responses vary by.
+	Vary []string
+}
+
+// String returns the Cache-Control header value of cc.
+func (cc CacheControl) String() string {
+	if cc.NoStore {
+		return "no-store"
+	}
+	var directives []string
+	if cc.Public {
+		directives = append(directives, "public")
+	}
+	if cc.Private {
+		directives = append(directives, "private")
+	}
+	for _, d := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"max-age", cc.MaxAge},
+		{"s-maxage", cc.SMaxAge},
+		{"stale-while-revalidate", cc.StaleWhileRevalidate},
+	} {
+		if d.duration > 0 {
+			directives = append(directives, d.name+"="+strconv.Itoa(ceilSeconds(d.duration)))
+		}
+	}
+	return strings.Join(directives, ", ")
+}
+
+func (cc CacheControl) validate() error {
+	if cc.Public && cc.Private {
+		return fmt.Errorf("cache control is both public and private")
+	}
+	if cc.MaxAge < 0 || cc.SMaxAge < 0 || cc.StaleWhileRevalidate < 0 {
+		return fmt.Errorf("cache control has a negative duration")
+	}
+	return nil
+}
+
+// CachePolicy declares the CacheControl of each operation of a resource,
+// the way Policy declares its authorization requirements: Create covers
+// Post and BulkPost, Update covers Put, Patch and BulkPatch, and Delete
+// covers Delete and BulkDelete. Custom actions are looked up in Actions by
+// name; actions without an entry use Default, as do operations whose field
+// is nil. Operations without a CacheControl get no caching headers.
+type CachePolicy struct {
+	Default *CacheControl
+	List    *CacheControl
+	Get     *CacheControl
+	Create  *CacheControl
+	Update  *CacheControl
+	Delete  *CacheControl
+	Actions map[string]CacheControl
+}
+
+// control returns the CacheControl of the named operation, or nil.
+func (p CachePolicy) control(name string) *CacheControl {
+	return forOperation(name, p.Default, p.List, p.Get, p.Create, p.Update, p.Delete, p.Actions)
+}
+
+// CachePolicyProvider is implemented by resources that declare the caching
+// policy of their responses.
+type CachePolicyProvider interface {
+	CachePolicy() CachePolicy
+}
+
+// WithCachePolicy sets the caching policy of the resource's responses,
+// overriding the CachePolicy method of the resource if it has one.
+func WithCachePolicy(policy CachePolicy) ResourceOption {
+	return func(rc *resourceConfig) {
+		rc.cachePolicy = &policy
+	}
+}
+
+// withCachePolicy sets the Cache-Control and Vary headers declared for the
+// named operation on its successful responses, unless the handler set them
+// itself. Error responses never get them. Panics if the declaration is
+// invalid.
+func withCachePolicy(entry *resourceEntry, name string, fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	policy := entry.config.cachePolicy
+	if policy == nil {
+		provider, ok := entry.resource.(CachePolicyProvider)
+		if !ok {
+			return fn
+		}
+		p := provider.CachePolicy()
+		policy = &p
+	}
+	cc := policy.control(name)
+	if cc == nil {
+		return fn
+	}
+	if err := cc.validate(); err != nil {
+		panic(fmt.Sprintf("gin-restful: invalid cache policy for %s of %q: %v", name, entry.path, err))
+	}
+	cacheControl := cc.String()
+	vary := strings.Join(cc.Vary, ", ")
+
+	return func(c *gin.Context) (any, int, error) {
+		result, status, err := fn(c)
+		if err != nil || status >= http.StatusBadRequest || c.IsAborted() {
+			return result, status, err
+		}
+		header := c.Writer.Header()
+		var set []string
+		if cacheControl != "" && header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", cacheControl)
+			set = append(set, "Cache-Control")
+		}
+		if vary != "" && header.Get("Vary") == "" {
+			header.Set("Vary", vary)
+			set = append(set, "Vary")
+		}
+		if set != nil {
+			c.Set(cachePolicyHeadersKey, set)
+		}
+		return result, status, err
+	}
+}
+
+// cachePolicyHeadersKey holds the names of the headers withCachePolicy set.
+const cachePolicyHeadersKey = "gin-restful.cache-policy-headers"
+
+// dropCachePolicy removes the headers withCachePolicy set from the
+// response, whose result turned out to be an error, as with a stream
+// failing before its first item.
+func dropCachePolicy(c *gin.Context) {
+	if v, ok := c.Get(cachePolicyHeadersKey); ok {
+		for _, name := range v.([]string) {
+			c.Writer.Header().Del(name)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package restful
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+// catalogPageResource declares a caching policy.
+type catalogPageResource struct {
+	reads int
+}
+
+func (r *catalogPageResource) CachePolicy() CachePolicy {
+	return CachePolicy{
+		Default: &CacheControl{NoStore: true},
+		List:    &CacheControl{Public: true, MaxAge: time.Minute, StaleWhileRevalidate: 30 * time.Second},
+		Get:     &CacheControl{Private: true, MaxAge: 10 * time.Second, Vary: []string{"Accept-Language", "Authorization"}},
+		Actions: map[string]CacheControl{"stats": {Public: true, SMaxAge: time.Hour}},
+	}
+}
+
+func (r *catalogPageResource) Actions() []Action {
+	return []Action{
+		CollectionAction(http.MethodGet, "stats", func(c *gin.Context) (any, int, error) {
+			return gin.H{}, http.StatusOK, nil
+		}),
+		CollectionAction(http.MethodPost, "reindex", func(c *gin.Context) (any, int, error) {
+			return gin.H{}, http.StatusAccepted, nil
+		}),
+	}
+}
+
+func (r *catalogPageResource) List(c *gin.Context) (any, int, error) {
+	r.reads++
+	return []gin.H{{"read": r.reads}}, http.StatusOK, nil
+}
+
+func (r *catalogPageResource) Get(id string, c *gin.Context) (any, int, error) {
+	switch id {
+	case "missing":
+		return nil, 0, Abort(http.StatusNotFound, "not found")
+	case "custom":
+		c.Header("Cache-Control", "no-cache")
+	}
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+func (r *catalogPageResource) Post(c *gin.Context) (any, int, error) {
+	return gin.H{"id": "1"}, http.StatusCreated, nil
+}
+
+// --- tests ---
+
+func TestCacheControl_String(t *testing.T) {
+	tests := []struct {
+		cc   CacheControl
+		want string
+	}{
+		{CacheControl{}, ""},
+		{CacheControl{NoStore: true, Public: true, MaxAge: time.Minute}, "no-store"},
+		{CacheControl{Public: true, MaxAge: time.Minute, SMaxAge: 2 * time.Minute}, "public, max-age=60, s-maxage=120"},
+		{CacheControl{Private: true, MaxAge: 1500 * time.Millisecond, StaleWhileRevalidate: time.Second}, "private, max-age=2, stale-while-revalidate=1"},
+	}
+	for _, tt := range tests {
+		if got := tt.cc.String(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestCachePolicy_SetsHeaders(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/catalog", &catalogPageResource{})
+
+	tests := []struct {
+		method, path       string
+		status             int
+		cacheControl, vary string
+	}{
+		{"GET", "/api/catalog", http.StatusOK, "public, max-age=60, stale-while-revalidate=30", ""},
+		{"GET", "/api/catalog/1", http.StatusOK, "private, max-age=10", "Accept-Language, Authorization"},
+		{"GET", "/api/catalog/stats", http.StatusOK, "public, s-maxage=3600", ""},
+		{"POST", "/api/catalog", http.StatusCreated, "no-store", ""},
+		{"POST", "/api/catalog/reindex", http.StatusAccepted, "no-store", ""},
+		// Errors never get the policy's headers, and handlers may override them.
+		{"GET", "/api/catalog/missing", http.StatusNotFound, "", ""},
+		{"GET", "/api/catalog/custom", http.StatusOK, "no-cache", "Accept-Language, Authorization"},
+	}
+	for _, tt := range tests {
+		w := doRequest(engine, tt.method, tt.path, `{}`)
+		if w.Code != tt.status || w.Header().Get("Cache-Control") != tt.cacheControl || w.Header().Get("Vary") != tt.vary {
+			t.Errorf("%s %s: expected %d %q %q, got %d %q %q", tt.method, tt.path,
+				tt.status, tt.cacheControl, tt.vary, w.Code, w.Header().Get("Cache-Control"), w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestCachePolicy_SkipsFailedPolicyChecks(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/products", &adminCatalogResource{newCatalogResource()},
+		WithCachePolicy(CachePolicy{Default: &CacheControl{Public: true, MaxAge: time.Minute}}))
+
+	w := doRequest(engine, "GET", "/api/products/1", "")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("expected a 401 without Cache-Control, got %d %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestCachePolicy_OptionOverridesMethod(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/catalog", &catalogPageResource{},
+		WithCachePolicy(CachePolicy{List: &CacheControl{NoStore: true}}))
+
+	if w := doRequest(engine, "GET", "/api/catalog", ""); w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("expected the option's policy, got %q", w.Header().Get("Cache-Control"))
+	}
+	if w := doRequest(engine, "GET", "/api/catalog/1", ""); w.Header().Get("Cache-Control") != "" {
+		t.Errorf("expected no Cache-Control, got %q", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestCachePolicy_DrivesResponseCache(t *testing.T) {
+	resource := &catalogPageResource{}
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/catalog", resource, WithResponseCache())
+
+	doRequest(engine, "GET", "/api/catalog", "")
+	w := doRequest(engine, "GET", "/api/catalog", "")
+	if resource.reads != 1 || w.Header().Get("Cache-Control") != "public, max-age=60, stale-while-revalidate=30" {
+		t.Errorf("expected the cached response, got %d reads and %q", resource.reads, w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestCachePolicy_InvalidPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	api := NewAPI(gin.New(), "/api")
+	api.AddResource("/catalog", &catalogPageResource{},
+		WithCachePolicy(CachePolicy{Default: &CacheControl{Public: true, Private: true}}))
+}
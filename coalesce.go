@@ -0,0 +1,255 @@
+package restful
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CoalesceKeyFunc returns the part of the coalescing key that depends on
+// the caller, such as its principal. Requests are only coalesced when all
+// key functions agree.
+type CoalesceKeyFunc func(c *gin.Context) string
+
+// CoalesceByPrincipal separates requests of different principals set with
+// SetPrincipal. It is the default.
+func CoalesceByPrincipal(c *gin.Context) string {
+	if p, ok := PrincipalFrom(c); ok {
+		return p.Subject
+	}
+	return ""
+}
+
+// CoalesceByHeader separates requests with different values of the given
+// header, e.g. Accept-Language.
+func CoalesceByHeader(name string) CoalesceKeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// CoalesceOption configures WithCoalescing.
+type CoalesceOption func(*coalesceConfig)
+
+type coalesceConfig struct {
+	vary []CoalesceKeyFunc
+}
+
+// WithCoalesceVary sets what separates the requests of different callers,
+// replacing the default CoalesceByPrincipal. Without any function, requests
+// of all callers are coalesced, which only suits public data.
+func WithCoalesceVary(vary ...CoalesceKeyFunc) CoalesceOption {
+	return func(cfg *coalesceConfig) {
+		cfg.vary = vary
+	}
+}
+
+// WithCoalescing collapses concurrent identical Get and List requests to
+// the resource into one call of its handler, whose result, status and
+// error are shared by all of them, singleflight-style. Requests are
+// identical when they have the same route, path parameters, query
+// parameters in any order and, by default, principal. Authorization, hooks,
+// row filters and representation still run for each request. Results must
+// therefore not be modified by hooks, and headers set by the handler only
+// reach the request that ran it. The shared call runs until it completes,
+// every request waiting for it has gone, or the deadline of the request
+// that started it passes.
+func WithCoalescing(opts ...CoalesceOption) ResourceOption {
+	cfg := &coalesceConfig{vary: []CoalesceKeyFunc{CoalesceByPrincipal}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(rc *resourceConfig) {
+		rc.coalesce = cfg
+	}
+}
+
+// coalescer tracks the calls in flight of one resource.
+type coalescer struct {
+	cfg   *coalesceConfig
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	refs     int  // requests waiting for the call, guarded by coalescer.mu
+	finished bool // guarded by coalescer.mu
+
+	result any
+	status int
+	err    error
+}
+
+// coalesce returns fn sharing its calls between identical concurrent
+// requests, or fn itself when entry does not use WithCoalescing.
+func coalesce(entry *resourceEntry, fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	if entry.config.coalesce == nil {
+		return fn
+	}
+	if entry.coalescer == nil {
+		entry.coalescer = &coalescer{cfg: entry.config.coalesce, calls: make(map[string]*coalescedCall)}
+	}
+	return entry.coalescer.wrap(fn)
+}
+
+func (g *coalescer) wrap(fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	return func(c *gin.Context) (any, int, error) {
+		key := g.key(c)
+		ctx := c.Request.Context()
+		scope, ok := ctx.Value(coalesceScopeKey{}).(*coalesceScope)
+		if !ok {
+			return fn(c)
+		}
+
+		g.mu.Lock()
+		if call, ok := g.calls[key]; ok {
+			call.refs++
+			g.mu.Unlock()
+			select {
+			case <-call.done:
+				return call.result, call.status, call.err
+			case <-ctx.Done():
+				g.leave(call)
+				return nil, 0, ctx.Err()
+			}
+		}
+		call := &coalescedCall{done: make(chan struct{}), cancel: scope.cancel, refs: 1}
+		g.calls[key] = call
+		g.mu.Unlock()
+
+		// The call outlives the request that started it as long as others
+		// wait for it.
+		attach := scope.detach(func() {
+			g.leave(call)
+		})
+		defer func() {
+			attach()
+			if v := recover(); v != nil {
+				call.result, call.status, call.err = nil, 0, &PanicError{Value: v, Stack: debug.Stack()}
+				g.finish(key, call)
+				panic(v)
+			}
+			g.finish(key, call)
+		}()
+		call.result, call.status, call.err = fn(c)
+		return call.result, call.status, call.err
+	}
+}
+
+// key identifies the requests that may share a call.
+func (g *coalescer) key(c *gin.Context) string {
+	var b strings.Builder
+	b.WriteString(routeKey(c))
+	for _, vary := range g.cfg.vary {
+		b.WriteString("\x00" + vary(c))
+	}
+	return b.String()
+}
+
+// routeKey identifies the resource a GET request reads: its route, path
+// parameters and query parameters in any order.
+func routeKey(c *gin.Context) string {
+	var b strings.Builder
+	b.WriteString(c.FullPath())
+	for _, p := range c.Params {
+		b.WriteString("\x00" + p.Key + "=" + p.Value)
+	}
+	// Encode sorts the parameters by name.
+	b.WriteString("\x00" + c.Request.URL.Query().Encode())
+	return b.String()
+}
+
+// leave drops a request's interest in call, canceling it when no request
+// is left.
+func (g *coalescer) leave(call *coalescedCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call.refs--
+	if call.refs == 0 && !call.finished {
+		call.cancel()
+	}
+}
+
+func (g *coalescer) finish(key string, call *coalescedCall) {
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	call.finished = true
+	g.mu.Unlock()
+	close(call.done)
+}
+
+// coalesceScope holds the context of a request to a coalescing route. The
+// context is set up before the handler runs, possibly in the goroutine of
+// a timeout, so that the call the request starts can outlive it without
+// c.Request being replaced under the handler.
+type coalesceScope struct {
+	parent context.Context
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	gone func() // called instead of cancel while a call is detached
+}
+
+type coalesceScopeKey struct{}
+
+// withCoalesceScope runs h with a request context that keeps the deadline
+// of the request's and is canceled with it, unless the coalesced call the
+// request started is detached from it.
+func withCoalesceScope(h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parent := c.Request.Context()
+		ctx := context.WithoutCancel(parent)
+		var cancel context.CancelFunc
+		if deadline, ok := parent.Deadline(); ok {
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+		defer cancel()
+		scope := &coalesceScope{parent: parent, cancel: cancel}
+		stop := context.AfterFunc(parent, scope.requestGone)
+		defer stop()
+		c.Request = c.Request.WithContext(context.WithValue(ctx, coalesceScopeKey{}, scope))
+		h(c)
+	}
+}
+
+func (s *coalesceScope) requestGone() {
+	s.mu.Lock()
+	gone := s.gone
+	s.mu.Unlock()
+	if gone != nil {
+		gone()
+		return
+	}
+	s.cancel()
+}
+
+// detach makes the request going away call gone instead of canceling the
+// context, until the returned function attaches it again.
+func (s *coalesceScope) detach(gone func()) (attach func()) {
+	s.mu.Lock()
+	s.gone = gone
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		s.gone = nil
+		s.mu.Unlock()
+		if s.parent.Err() != nil {
+			s.cancel()
+		}
+	}
+}
+
+// coalesces reports whether the named operation of entry coalesces its
+// calls.
+func coalesces(entry *resourceEntry, name string) bool {
+	return entry.coalescer != nil && (name == "Get" || name == "List")
+}
@@ -0,0 +1,360 @@
+package restful
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+// catalogResource blocks its calls until released, counting them.
+type catalogResource struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+	ctxErr  chan error
+}
+
+func newCatalogResource() *catalogResource {
+	return &catalogResource{started: make(chan struct{}, 10), release: make(chan struct{}), ctxErr: make(chan error, 10)}
+}
+
+func (r *catalogResource) wait(c *gin.Context) {
+	r.calls.Add(1)
+	r.started <- struct{}{}
+	select {
+	case <-r.release:
+	case <-c.Request.Context().Done():
+		r.ctxErr <- c.Request.Context().Err()
+	}
+}
+
+func (r *catalogResource) List(c *gin.Context) (any, int, error) {
+	r.wait(c)
+	return []gin.H{{"id": "1"}, {"id": "2"}}, http.StatusOK, nil
+}
+
+func (r *catalogResource) Get(id string, c *gin.Context) (any, int, error) {
+	r.wait(c)
+	if id == "missing" {
+		return nil, 0, Abort(http.StatusNotFound, "product not found")
+	}
+	return gin.H{"id": id, "calls": r.calls.Load()}, http.StatusOK, nil
+}
+
+// adminCatalogResource requires the admin role.
+type adminCatalogResource struct {
+	*catalogResource
+}
+
+func (r *adminCatalogResource) Policy() Policy {
+	return Policy{Default: &Requirement{Roles: []string{"admin"}}}
+}
+
+// --- helpers ---
+
+func setupCoalescingRouter(resource any, opts ...CoalesceOption) (*gin.Engine, *API) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			SetPrincipal(c, &Principal{Subject: user})
+		}
+	})
+	api.AddResource("/products", resource, WithCoalescing(opts...))
+	return engine, api
+}
+
+// waitForWaiters waits until n requests wait for calls of the resource.
+func waitForWaiters(t *testing.T, api *API, n int) {
+	t.Helper()
+	g := api.resources[0].coalescer
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		g.mu.Lock()
+		refs := 0
+		for _, call := range g.calls {
+			refs += call.refs
+		}
+		g.mu.Unlock()
+		if refs == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting requests, got %d", n, refs)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func goRequestWithHeaders(engine *gin.Engine, path string, headers map[string]string) (w *httptest.ResponseRecorder, done chan struct{}) {
+	w, done = httptest.NewRecorder(), make(chan struct{})
+	req := httptest.NewRequest("GET", path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	go func() {
+		engine.ServeHTTP(w, req)
+		close(done)
+	}()
+	return w, done
+}
+
+// --- tests ---
+
+func TestCoalescing_SharesGet(t *testing.T) {
+	resource := newCatalogResource()
+	engine, api := setupCoalescingRouter(resource)
+
+	var responses []*httptest.ResponseRecorder
+	var dones []chan struct{}
+	for range 3 {
+		w, done := goRequest(engine, "/api/products/1")
+		responses, dones = append(responses, w), append(dones, done)
+	}
+	<-resource.started
+	waitForWaiters(t, api, 3)
+	close(resource.release)
+	for _, done := range dones {
+		<-done
+	}
+
+	if n := resource.calls.Load(); n != 1 {
+		t.Errorf("expected a single call, got %d", n)
+	}
+	for _, w := range responses {
+		if w.Code != http.StatusOK || w.Body.String() != `{"calls":1,"id":"1"}` {
+			t.Errorf("expected the shared result, got %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Once done, the call is not reused.
+	w := doRequest(engine, "GET", "/api/products/1", "")
+	if w.Body.String() != `{"calls":2,"id":"1"}` {
+		t.Errorf("expected a new call, got %s", w.Body.String())
+	}
+}
+
+func TestCoalescing_SharesErrors(t *testing.T) {
+	resource := newCatalogResource()
+	engine, api := setupCoalescingRouter(resource)
+
+	first, done1 := goRequest(engine, "/api/products/missing")
+	second, done2 := goRequest(engine, "/api/products/missing")
+	<-resource.started
+	waitForWaiters(t, api, 2)
+	close(resource.release)
+	<-done1
+	<-done2
+
+	if n := resource.calls.Load(); n != 1 {
+		t.Errorf("expected a single call, got %d", n)
+	}
+	for _, w := range []*httptest.ResponseRecorder{first, second} {
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCoalescing_NormalizesQuery(t *testing.T) {
+	resource := newCatalogResource()
+	engine, api := setupCoalescingRouter(resource)
+
+	_, done1 := goRequest(engine, "/api/products?sort=name&page=2")
+	_, done2 := goRequest(engine, "/api/products?page=2&sort=name")
+	<-resource.started
+	waitForWaiters(t, api, 2)
+	close(resource.release)
+	<-done1
+	<-done2
+
+	if n := resource.calls.Load(); n != 1 {
+		t.Errorf("expected reordered queries to share a call, got %d calls", n)
+	}
+}
+
+func TestCoalescing_SeparatesKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+	}{
+		{"id", "/api/products/2", map[string]string{"X-User": "alice"}},
+		{"query", "/api/products/1?fields=id", map[string]string{"X-User": "alice"}},
+		{"principal", "/api/products/1", map[string]string{"X-User": "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := newCatalogResource()
+			engine, api := setupCoalescingRouter(resource)
+
+			_, done1 := goRequestWithHeaders(engine, "/api/products/1", map[string]string{"X-User": "alice"})
+			<-resource.started
+			_, done2 := goRequestWithHeaders(engine, tt.path, tt.header)
+			<-resource.started
+			waitForWaiters(t, api, 2)
+			close(resource.release)
+			<-done1
+			<-done2
+
+			if n := resource.calls.Load(); n != 2 {
+				t.Errorf("expected separate calls, got %d", n)
+			}
+		})
+	}
+}
+
+func TestCoalescing_Vary(t *testing.T) {
+	resource := newCatalogResource()
+	engine, api := setupCoalescingRouter(resource, WithCoalesceVary(CoalesceByHeader("Accept-Language")))
+
+	_, done1 := goRequestWithHeaders(engine, "/api/products/1", map[string]string{"X-User": "alice", "Accept-Language": "en"})
+	_, done2 := goRequestWithHeaders(engine, "/api/products/1", map[string]string{"X-User": "bob", "Accept-Language": "en"})
+	<-resource.started
+	waitForWaiters(t, api, 2)
+	_, done3 := goRequestWithHeaders(engine, "/api/products/1", map[string]string{"Accept-Language": "ko"})
+	<-resource.started
+	close(resource.release)
+	<-done1
+	<-done2
+	<-done3
+
+	if n := resource.calls.Load(); n != 2 {
+		t.Errorf("expected one call per language, got %d", n)
+	}
+}
+
+func TestCoalescing_RunsPolicyPerRequest(t *testing.T) {
+	resource := &adminCatalogResource{newCatalogResource()}
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user == "alice" {
+			SetPrincipal(c, &Principal{Subject: user, Roles: []string{"admin"}})
+		} else {
+			SetPrincipal(c, &Principal{Subject: user})
+		}
+	})
+	api.AddResource("/products", resource, WithCoalescing(WithCoalesceVary()))
+
+	alice, done := goRequestWithHeaders(engine, "/api/products/1", map[string]string{"X-User": "alice"})
+	<-resource.started
+	w := doRequestWithHeaders(engine, "GET", "/api/products/1", map[string]string{"X-User": "bob"})
+	close(resource.release)
+	<-done
+
+	if alice.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", alice.Code)
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected the policy to reject the second request, got %d", w.Code)
+	}
+}
+
+func TestCoalescing_LeaderCanceled(t *testing.T) {
+	resource := newCatalogResource()
+	engine, api := setupCoalescingRouter(resource)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := httptest.NewRecorder()
+	leaderDone := make(chan struct{})
+	go func() {
+		engine.ServeHTTP(leader, httptest.NewRequest("GET", "/api/products/1", nil).WithContext(ctx))
+		close(leaderDone)
+	}()
+	<-resource.started
+	follower, followerDone := goRequest(engine, "/api/products/1")
+	waitForWaiters(t, api, 2)
+
+	// The call goes on for the follower.
+	cancel()
+	waitForWaiters(t, api, 1)
+	close(resource.release)
+	<-leaderDone
+	<-followerDone
+
+	if follower.Code != http.StatusOK {
+		t.Errorf("expected the follower to get the result, got %d %s", follower.Code, follower.Body.String())
+	}
+}
+
+func TestCoalescing_CanceledWhenAllLeave(t *testing.T) {
+	resource := newCatalogResource()
+	engine, api := setupCoalescingRouter(resource)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	done := make(chan struct{}, 2)
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		go func() {
+			engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/products/1", nil).WithContext(ctx))
+			done <- struct{}{}
+		}()
+	}
+	<-resource.started
+	waitForWaiters(t, api, 2)
+
+	cancel2()
+	cancel1()
+	select {
+	case err := <-resource.ctxErr:
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the shared call to be canceled")
+	}
+	<-done
+	<-done
+}
+
+func TestCoalescing_Disabled(t *testing.T) {
+	resource := newCatalogResource()
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/products", resource)
+	if api.resources[0].coalescer != nil {
+		t.Fatal("expected no coalescer without WithCoalescing")
+	}
+
+	_, done1 := goRequest(engine, "/api/products/1")
+	_, done2 := goRequest(engine, "/api/products/1")
+	<-resource.started
+	<-resource.started
+	close(resource.release)
+	<-done1
+	<-done2
+
+	if n := resource.calls.Load(); n != 2 {
+		t.Errorf("expected two calls, got %d", n)
+	}
+}
+
+func TestCoalescing_KeepsDeadline(t *testing.T) {
+	resource := newCatalogResource()
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/products", resource, WithCoalescing(), WithTimeout(20*time.Millisecond))
+
+	w, done := goRequest(engine, "/api/products/1")
+	select {
+	case err := <-resource.ctxErr:
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		close(resource.release)
+		t.Fatal("expected the shared call to end at the deadline")
+	}
+	<-done
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected a timeout, got %d %s", w.Code, w.Body.String())
+	}
+}
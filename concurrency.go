@@ -0,0 +1,243 @@
+package restful
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyStats is the state of the concurrency limiter of a resource.
+type ConcurrencyStats struct {
+	Resource string
+	// Limit is the number of calls allowed to run at once. It varies in
+	// adaptive mode.
+	Limit int
+	// Active is the number of calls running.
+	Active int
+	// Queued is the number of calls waiting for a slot.
+	Queued int
+	// Shed is the number of calls rejected since the limiter was created.
+	Shed uint64
+}
+
+// ConcurrencyObserver is implemented by Observers that also track the
+// concurrency limiters set with WithConcurrencyLimit. ConcurrencyChanged is
+// called with the new state of a limiter whenever it changes, while the
+// limiter is locked, so it must be fast.
+type ConcurrencyObserver interface {
+	ConcurrencyChanged(stats ConcurrencyStats)
+}
+
+// ConcurrencyOption configures WithConcurrencyLimit.
+type ConcurrencyOption func(*concurrencyConfig)
+
+type concurrencyConfig struct {
+	limit        int
+	queueSize    int
+	queueTimeout time.Duration
+	target       time.Duration
+	minLimit     int
+	retryAfter   time.Duration
+	operations   []string
+}
+
+// WithConcurrencyQueue lets up to size calls wait for a slot when the limit
+// is reached, each for at most timeout, or until the client goes away when
+// timeout is zero. Without a queue, calls over the limit are rejected
+// immediately.
+func WithConcurrencyQueue(size int, timeout time.Duration) ConcurrencyOption {
+	return func(cfg *concurrencyConfig) {
+		cfg.queueSize, cfg.queueTimeout = size, timeout
+	}
+}
+
+// WithAdaptiveConcurrency makes the limit follow the observed latency: it
+// grows by one call per limit calls completing within target, up to the
+// configured limit, and shrinks by 10% for every call slower than target,
+// down to minLimit.
+func WithAdaptiveConcurrency(target time.Duration, minLimit int) ConcurrencyOption {
+	return func(cfg *concurrencyConfig) {
+		cfg.target, cfg.minLimit = target, minLimit
+	}
+}
+
+// WithConcurrencyRetryAfter sets the Retry-After of rejected calls. The
+// default is one second.
+func WithConcurrencyRetryAfter(d time.Duration) ConcurrencyOption {
+	return func(cfg *concurrencyConfig) {
+		cfg.retryAfter = d
+	}
+}
+
+// WithConcurrencyOperations limits only the named operations ("List",
+// "Get", an action name, ...) instead of all of them. They share the limit.
+func WithConcurrencyOperations(operations ...string) ConcurrencyOption {
+	return func(cfg *concurrencyConfig) {
+		cfg.operations = append(cfg.operations, operations...)
+	}
+}
+
+// WithConcurrencyLimit limits the number of calls to the resource running
+// at once to limit. Calls over the limit are queued if WithConcurrencyQueue
+// is used, and otherwise, or when the queue is full or their wait times
+// out, rejected with 503 Service Unavailable, the code "OVERLOADED" and a
+// Retry-After header. Its state is reported to the API's observers that
+// implement ConcurrencyObserver.
+func WithConcurrencyLimit(limit int, opts ...ConcurrencyOption) ResourceOption {
+	cfg := &concurrencyConfig{limit: limit, retryAfter: time.Second}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if limit <= 0 || cfg.queueSize < 0 || cfg.queueTimeout < 0 || (cfg.target > 0 && (cfg.minLimit <= 0 || cfg.minLimit > limit)) {
+		panic(fmt.Sprintf("gin-restful: invalid concurrency limit of %d (queue %d, min %d)", limit, cfg.queueSize, cfg.minLimit))
+	}
+	return func(rc *resourceConfig) {
+		rc.concurrency = cfg
+	}
+}
+
+// applies reports whether the limiter applies to the named operation.
+func (cfg *concurrencyConfig) applies(name string) bool {
+	return len(cfg.operations) == 0 || slices.Contains(cfg.operations, name)
+}
+
+// concurrencyLimiter is the semaphore of one resource. Queued calls are
+// served in arrival order: a released slot is handed over to the first
+// waiter rather than freed.
+type concurrencyLimiter struct {
+	cfg       *concurrencyConfig
+	resource  string
+	observers []ConcurrencyObserver
+
+	mu     sync.Mutex
+	limit  float64
+	active int
+	queue  *list.List // of chan struct{}, closed when granted a slot
+	shed   uint64
+}
+
+func newConcurrencyLimiter(cfg *concurrencyConfig, resource string, observers []Observer) *concurrencyLimiter {
+	l := &concurrencyLimiter{cfg: cfg, resource: resource, limit: float64(cfg.limit), queue: list.New()}
+	for _, o := range observers {
+		if co, ok := o.(ConcurrencyObserver); ok {
+			l.observers = append(l.observers, co)
+		}
+	}
+	l.mu.Lock()
+	l.report()
+	l.mu.Unlock()
+	return l
+}
+
+// handler is the middleware holding a slot while the rest of the chain runs.
+func (l *concurrencyLimiter) handler(c *gin.Context) {
+	if err := l.acquire(c.Request.Context()); err != nil {
+		RenderError(c, err)
+		return
+	}
+	start := time.Now()
+	defer func() {
+		l.release(time.Since(start))
+	}()
+	c.Next()
+}
+
+func (l *concurrencyLimiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if l.active < int(l.limit) && l.queue.Len() == 0 {
+		l.active++
+		l.report()
+		l.mu.Unlock()
+		return nil
+	}
+	if l.queue.Len() >= l.cfg.queueSize {
+		err := l.reject()
+		l.mu.Unlock()
+		return err
+	}
+	granted := make(chan struct{})
+	elem := l.queue.PushBack(granted)
+	l.report()
+	l.mu.Unlock()
+
+	var expired <-chan time.Time
+	if l.cfg.queueTimeout > 0 {
+		timer := time.NewTimer(l.cfg.queueTimeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-granted:
+		return nil
+	case <-expired:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-granted:
+		// The slot was handed over while giving up.
+		return nil
+	default:
+	}
+	l.queue.Remove(elem)
+	if err := ctx.Err(); err != nil {
+		l.report()
+		return err
+	}
+	return l.reject()
+}
+
+// reject counts a shed call and returns its error. l.mu must be held.
+func (l *concurrencyLimiter) reject() error {
+	l.shed++
+	l.report()
+	return Abort(http.StatusServiceUnavailable, "server is overloaded, retry later",
+		WithCode("OVERLOADED"),
+		WithHeader("Retry-After", strconv.Itoa(ceilSeconds(l.cfg.retryAfter))),
+	)
+}
+
+func (l *concurrencyLimiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.target > 0 {
+		if latency > l.cfg.target {
+			l.limit = max(float64(l.cfg.minLimit), l.limit*0.9)
+		} else {
+			l.limit = min(float64(l.cfg.limit), l.limit+1/l.limit)
+		}
+	}
+	l.active--
+	for l.active < int(l.limit) && l.queue.Len() > 0 {
+		l.active++
+		close(l.queue.Remove(l.queue.Front()).(chan struct{}))
+	}
+	l.report()
+}
+
+// report sends the state to the observers. l.mu must be held.
+func (l *concurrencyLimiter) report() {
+	if len(l.observers) == 0 {
+		return
+	}
+	stats := ConcurrencyStats{
+		Resource: l.resource,
+		Limit:    int(l.limit),
+		Active:   l.active,
+		Queued:   l.queue.Len(),
+		Shed:     l.shed,
+	}
+	for _, o := range l.observers {
+		o.ConcurrencyChanged(stats)
+	}
+}
@@ -0,0 +1,219 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+// exportResource blocks List calls until released, signalling each start.
+type exportResource struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newExportResource() *exportResource {
+	return &exportResource{started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (r *exportResource) List(c *gin.Context) (any, int, error) {
+	r.started <- struct{}{}
+	<-r.release
+	return []string{}, http.StatusOK, nil
+}
+
+func (r *exportResource) Get(id string, c *gin.Context) (any, int, error) {
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+type statsRecorder struct {
+	mu    sync.Mutex
+	stats []ConcurrencyStats
+}
+
+func (r *statsRecorder) StartCall(c *gin.Context, op Operation) func(CallResult) { return nil }
+
+func (r *statsRecorder) ConcurrencyChanged(stats ConcurrencyStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats = append(r.stats, stats)
+}
+
+func (r *statsRecorder) last() ConcurrencyStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats[len(r.stats)-1]
+}
+
+// importResource blocks BulkPost calls until released, signalling each
+// start; single Posts return at once.
+type importResource struct {
+	*exportResource
+}
+
+func (r importResource) Post(c *gin.Context) (any, int, error) {
+	return gin.H{}, http.StatusCreated, nil
+}
+
+func (r importResource) BulkPost(items []json.RawMessage, c *gin.Context) ([]BulkResult, error) {
+	r.started <- struct{}{}
+	<-r.release
+	return make([]BulkResult, len(items)), nil
+}
+
+// goRequest serves a GET in the background and returns its response once
+// done is closed.
+func goRequest(engine *gin.Engine, path string) (w *httptest.ResponseRecorder, done chan struct{}) {
+	w, done = httptest.NewRecorder(), make(chan struct{})
+	go func() {
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		close(done)
+	}()
+	return w, done
+}
+
+// --- tests ---
+
+func TestConcurrencyLimit_Sheds(t *testing.T) {
+	resource := newExportResource()
+	stats := &statsRecorder{}
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithObserver(stats))
+	api.AddResource("/exports", resource, WithConcurrencyLimit(1, WithConcurrencyRetryAfter(5*time.Second)))
+
+	first, done := goRequest(engine, "/api/exports")
+	<-resource.started
+
+	w := doRequest(engine, "GET", "/api/exports", "")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "5" ||
+		w.Body.String() != `{"message":"server is overloaded, retry later","code":"OVERLOADED"}` {
+		t.Errorf("expected the call to be shed, got %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+	if s := stats.last(); s.Resource != "exports" || s.Limit != 1 || s.Active != 1 || s.Shed != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	close(resource.release)
+	<-done
+	if first.Code != http.StatusOK {
+		t.Errorf("expected the first call to succeed, got %d", first.Code)
+	}
+	if s := stats.last(); s.Active != 0 {
+		t.Errorf("expected the slot to be released, got %+v", s)
+	}
+}
+
+func TestConcurrencyLimit_Queue(t *testing.T) {
+	resource := newExportResource()
+	stats := &statsRecorder{}
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithObserver(stats))
+	api.AddResource("/exports", resource, WithConcurrencyLimit(1, WithConcurrencyQueue(1, time.Minute)))
+
+	_, firstDone := goRequest(engine, "/api/exports")
+	<-resource.started
+	queued, queuedDone := goRequest(engine, "/api/exports")
+	for stats.last().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if w := doRequest(engine, "GET", "/api/exports", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a full queue to shed, got %d", w.Code)
+	}
+
+	close(resource.release)
+	<-firstDone
+	<-queuedDone
+	if queued.Code != http.StatusOK {
+		t.Errorf("expected the queued call to run, got %d", queued.Code)
+	}
+}
+
+func TestConcurrencyLimit_QueueTimeout(t *testing.T) {
+	resource := newExportResource()
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/exports", resource, WithConcurrencyLimit(1, WithConcurrencyQueue(5, 10*time.Millisecond)))
+
+	_, done := goRequest(engine, "/api/exports")
+	<-resource.started
+	if w := doRequest(engine, "GET", "/api/exports", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the wait to time out, got %d", w.Code)
+	}
+	close(resource.release)
+	<-done
+}
+
+func TestConcurrencyLimit_Operations(t *testing.T) {
+	resource := newExportResource()
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/exports", resource, WithConcurrencyLimit(1, WithConcurrencyOperations("List")))
+
+	_, done := goRequest(engine, "/api/exports")
+	<-resource.started
+	if w := doRequest(engine, "GET", "/api/exports/1", ""); w.Code != http.StatusOK {
+		t.Errorf("expected Get not to be limited, got %d", w.Code)
+	}
+	close(resource.release)
+	<-done
+}
+
+func TestConcurrencyLimit_OperationsOnSharedRoute(t *testing.T) {
+	resource := importResource{newExportResource()}
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/imports", resource, WithConcurrencyLimit(1, WithConcurrencyOperations("BulkPost")))
+
+	done := make(chan struct{})
+	go func() {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/imports", strings.NewReader(`[{}]`)))
+		close(done)
+	}()
+	<-resource.started
+	if w := doRequest(engine, "POST", "/api/imports", `{}`); w.Code != http.StatusCreated {
+		t.Errorf("expected Post not to be limited, got %d", w.Code)
+	}
+	if w := doRequest(engine, "POST", "/api/imports", `[{}]`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the second BulkPost to be shed, got %d", w.Code)
+	}
+	close(resource.release)
+	<-done
+}
+
+func TestConcurrencyLimit_Adaptive(t *testing.T) {
+	cfg := &concurrencyConfig{limit: 10, target: 100 * time.Millisecond, minLimit: 2}
+	l := newConcurrencyLimiter(cfg, "exports", nil)
+
+	for range 30 {
+		l.active++
+		l.release(time.Second)
+	}
+	if l.limit != 2 {
+		t.Errorf("expected slow calls to shrink the limit to its minimum, got %v", l.limit)
+	}
+	for range 20 {
+		l.active++
+		l.release(time.Millisecond)
+	}
+	if l.limit <= 4 || l.limit > 10 {
+		t.Errorf("expected fast calls to grow the limit, got %v", l.limit)
+	}
+}
+
+func TestConcurrencyLimit_InvalidPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	WithConcurrencyLimit(5, WithAdaptiveConcurrency(time.Second, 10))
+}
@@ -0,0 +1,91 @@
+package restful
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the non-standard status recorded when the
+// client goes away before the response is sent, as popularized by nginx.
+const StatusClientClosedRequest = 499
+
+// ErrorMapper translates an error into an HTTPError, or returns nil to leave
+// it to the next mapper.
+type ErrorMapper func(err error) *HTTPError
+
+// WithErrorMapper adds mapper to the API's error mapping chain. Errors
+// returned by handlers that are not already *HTTPError values are passed
+// through the chain in registration order, followed by the built-in
+// mappings; the first HTTPError returned is sent instead of a 500. The
+// built-in mappings are context.Canceled to 499, context.DeadlineExceeded
+// to 504 and sql.ErrNoRows to 404.
+func WithErrorMapper(mapper ErrorMapper) APIOption {
+	return func(api *API) {
+		api.errorMappers = append(api.errorMappers, mapper)
+	}
+}
+
+// WithErrorMapping maps errors matching target according to errors.Is, such
+// as a repository's ErrNotFound, to status with the given code. The message
+// is target's own, so wrapping context added to the error is not exposed.
+func WithErrorMapping(target error, status int, code string) APIOption {
+	return WithErrorMapper(func(err error) *HTTPError {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return Abort(status, target.Error(), WithCode(code))
+	})
+}
+
+// WithErrorMappingAs maps errors of type T according to errors.As, such as a
+// *ValidationError, to status with the given code and the message of the
+// matched error.
+func WithErrorMappingAs[T error](status int, code string) APIOption {
+	return WithErrorMapper(func(err error) *HTTPError {
+		var target T
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return Abort(status, target.Error(), WithCode(code))
+	})
+}
+
+var defaultErrorMappers = []ErrorMapper{
+	func(err error) *HTTPError {
+		if errors.Is(err, context.Canceled) {
+			return Abort(StatusClientClosedRequest, "client closed request", WithCode("CANCELED"))
+		}
+		return nil
+	},
+	func(err error) *HTTPError {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return Abort(http.StatusGatewayTimeout, "request timed out", WithCode("TIMEOUT"))
+		}
+		return nil
+	},
+	func(err error) *HTTPError {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Abort(http.StatusNotFound, "not found", WithCode("NOT_FOUND"))
+		}
+		return nil
+	},
+}
+
+// mapError returns the HTTPError err maps to, or err itself when it already
+// is one or no mapper matches.
+func (api *API) mapError(err error) error {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return err
+	}
+	for _, mappers := range [][]ErrorMapper{api.errorMappers, defaultErrorMappers} {
+		for _, mapper := range mappers {
+			if mapped := mapper(err); mapped != nil {
+				return mapped
+			}
+		}
+	}
+	return err
+}
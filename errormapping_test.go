@@ -0,0 +1,133 @@
+package restful
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+var (
+	errTaskNotFound = errors.New("task not found")
+	errTaskLocked   = errors.New("task is locked")
+)
+
+type quotaError struct {
+	Used int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota exceeded (%d used)", e.Used)
+}
+
+type failingResource struct{}
+
+func (r *failingResource) Get(id string, c *gin.Context) (any, int, error) {
+	switch id {
+	case "missing":
+		return nil, 0, fmt.Errorf("repo: load task %s: %w", id, errTaskNotFound)
+	case "locked":
+		return nil, 0, errTaskLocked
+	case "quota":
+		return nil, 0, fmt.Errorf("create: %w", &quotaError{Used: 10})
+	case "norows":
+		return nil, 0, fmt.Errorf("scan: %w", sql.ErrNoRows)
+	case "canceled":
+		return nil, 0, context.Canceled
+	case "timeout":
+		return nil, 0, fmt.Errorf("query: %w", context.DeadlineExceeded)
+	case "http":
+		return nil, 0, Abort(http.StatusTeapot, "teapot")
+	}
+	return nil, 0, errors.New("unexpected")
+}
+
+// --- tests ---
+
+func TestErrorMapping(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api",
+		WithErrorMapping(errTaskNotFound, http.StatusNotFound, "TASK_NOT_FOUND"),
+		WithErrorMapping(errTaskLocked, http.StatusConflict, "TASK_LOCKED"),
+		WithErrorMappingAs[*quotaError](http.StatusForbidden, "QUOTA"),
+		// User mappings take precedence over the built-in ones.
+		WithErrorMapper(func(err error) *HTTPError {
+			if errors.Is(err, context.Canceled) {
+				return Abort(http.StatusServiceUnavailable, "canceled")
+			}
+			return nil
+		}),
+	)
+	api.AddResource("/tasks", &failingResource{})
+
+	tests := []struct {
+		id     string
+		status int
+		body   string
+	}{
+		{"missing", http.StatusNotFound, `{"message":"task not found","code":"TASK_NOT_FOUND"}`},
+		{"locked", http.StatusConflict, `{"message":"task is locked","code":"TASK_LOCKED"}`},
+		{"quota", http.StatusForbidden, `{"message":"quota exceeded (10 used)","code":"QUOTA"}`},
+		{"norows", http.StatusNotFound, `{"message":"not found","code":"NOT_FOUND"}`},
+		{"canceled", http.StatusServiceUnavailable, `{"message":"canceled"}`},
+		{"timeout", http.StatusGatewayTimeout, `{"message":"request timed out","code":"TIMEOUT"}`},
+		{"http", http.StatusTeapot, `{"message":"teapot"}`},
+		{"other", http.StatusInternalServerError, `{"message":"internal server error"}`},
+	}
+	for _, tt := range tests {
+		w := doRequest(engine, "GET", "/api/tasks/"+tt.id, "")
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %s, got %d %s", tt.id, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestErrorMapping_BuiltInDefaults(t *testing.T) {
+	engine := setupRouter("/tasks", &failingResource{})
+
+	if w := doRequest(engine, "GET", "/api/tasks/canceled", ""); w.Code != StatusClientClosedRequest {
+		t.Errorf("expected 499 for context.Canceled, got %d", w.Code)
+	}
+}
+
+func TestErrorMapping_CustomErrorHandlerReceivesMappedError(t *testing.T) {
+	var got error
+	engine := gin.New()
+	api := NewAPI(engine, "/api",
+		WithErrorMapping(errTaskNotFound, http.StatusNotFound, "TASK_NOT_FOUND"),
+		WithErrorHandler(func(c *gin.Context, err error, status int) {
+			got = err
+			c.Status(http.StatusNotFound)
+		}),
+	)
+	api.AddResource("/tasks", &failingResource{})
+
+	doRequest(engine, "GET", "/api/tasks/missing", "")
+	var httpErr *HTTPError
+	if !errors.As(got, &httpErr) || httpErr.Code != "TASK_NOT_FOUND" {
+		t.Errorf("expected the mapped HTTPError, got %v", got)
+	}
+}
+
+func TestErrorMapping_SendsMappedHeaders(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithErrorMapper(func(err error) *HTTPError {
+		var quota *quotaError
+		if !errors.As(err, &quota) {
+			return nil
+		}
+		return Abort(http.StatusTooManyRequests, "quota exceeded", WithHeader("Retry-After", "10"))
+	}))
+	api.AddResource("/tasks", &failingResource{})
+
+	w := doRequest(engine, "GET", "/api/tasks/quota", "")
+	if retryAfter := w.Header().Values("Retry-After"); w.Code != http.StatusTooManyRequests || len(retryAfter) != 1 || retryAfter[0] != "10" {
+		t.Errorf("expected a 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+}
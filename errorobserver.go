@@ -0,0 +1,126 @@
+package restful
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorEvent describes an error response sent by the API.
+type ErrorEvent struct {
+	// Operation is the resource operation that failed. It is the zero
+	// value for errors rendered by middleware before the operation started.
+	Operation Operation
+	// Err is the error as returned, before error mapping. Use errors.Is and
+	// errors.As to inspect its chain, e.g. for a *PanicError.
+	Err error
+	// Status is the status code of the response.
+	Status int
+	// RequestID is the ID of the request as returned by RequestID, if any.
+	RequestID string
+}
+
+// ErrorObserver is notified of every error response sent by the API, after
+// the error handler ran. Observers are meant for logging and monitoring:
+// they cannot change the response.
+type ErrorObserver func(c *gin.Context, event ErrorEvent)
+
+// WithErrorObserver adds an observer notified of every error response,
+// including errors rendered by middleware through RenderError.
+func WithErrorObserver(observer ErrorObserver) APIOption {
+	return func(api *API) {
+		api.errorObservers = append(api.errorObservers, observer)
+	}
+}
+
+// WithCorrelationID includes an ID in every error response body, as the
+// "correlation_id" member (the error "id" with WithJSONAPI), so that
+// clients can quote it in support requests. id returns the ID of a
+// request, such as its trace ID; when nil, the request ID is used. Errors
+// that are neither HTTPErrors nor mapped by an ErrorMapper reach the error
+// handler as a generic 500 HTTPError carrying the ID; the original error
+// is recorded with c.Error and passed to ErrorObservers.
+func WithCorrelationID(id func(c *gin.Context) string) APIOption {
+	return func(api *API) {
+		if id == nil {
+			id = RequestID
+		}
+		api.correlationID = id
+	}
+}
+
+// decorateErrorHandler wraps handler, or the default error handler when it
+// is nil, to apply the API's error mappings and correlation IDs before it
+// and notify the error observers after it.
+func (api *API) decorateErrorHandler(handler ErrorHandlerFunc) ErrorHandlerFunc {
+	if handler == nil {
+		handler = handleError
+	}
+	return func(c *gin.Context, err error, status int) {
+		rendered := api.mapError(err)
+		var httpErr, mapped *HTTPError
+		if !errors.As(err, &httpErr) && errors.As(rendered, &mapped) {
+			addErrorHeaders(c, mapped)
+		}
+		if api.correlationID != nil {
+			rendered = api.correlate(c, rendered, status)
+		}
+		handler(c, rendered, status)
+		c.Set(responseErrorKey, responseError{err: err, rendered: rendered})
+
+		if len(api.errorObservers) == 0 {
+			return
+		}
+		op, _ := GetOperation(c)
+		event := ErrorEvent{Operation: op, Err: err, Status: c.Writer.Status(), RequestID: RequestID(c)}
+		for _, observe := range api.errorObservers {
+			observe(c, event)
+		}
+	}
+}
+
+// correlate returns a copy of err carrying the request's correlation ID,
+// turning errors other than HTTPErrors into a generic one.
+func (api *API) correlate(c *gin.Context, err error, status int) error {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		correlated := *httpErr
+		httpErr = &correlated
+	} else {
+		_ = c.Error(err)
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		httpErr = &HTTPError{Status: status, Message: "internal server error"}
+	}
+	httpErr.CorrelationID = api.correlationID(c)
+	return httpErr
+}
+
+const responseErrorKey = "gin-restful.response-error"
+
+// responseError is the error a response was rendered from, as returned and
+// as passed to the error handler.
+type responseError struct {
+	err      error
+	rendered error
+}
+
+// code returns the error code of the rendered error, if any.
+func (e responseError) code() string {
+	var httpErr *HTTPError
+	if errors.As(e.rendered, &httpErr) {
+		return httpErr.Code
+	}
+	return ""
+}
+
+// responseErrorFrom returns the error the response to c was rendered from.
+func responseErrorFrom(c *gin.Context) (responseError, bool) {
+	v, ok := c.Get(responseErrorKey)
+	if !ok {
+		return responseError{}, false
+	}
+	return v.(responseError), true
+}
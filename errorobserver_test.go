@@ -0,0 +1,122 @@
+package restful
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+var errShared = Abort(http.StatusConflict, "conflict")
+
+type sharedErrorResource struct{}
+
+func (r *sharedErrorResource) Get(id string, c *gin.Context) (any, int, error) {
+	return nil, 0, errShared
+}
+
+// --- tests ---
+
+func TestErrorObserver(t *testing.T) {
+	var events []ErrorEvent
+	engine := gin.New()
+	api := NewAPI(engine, "/api",
+		WithErrorMapping(errTaskNotFound, http.StatusNotFound, "TASK_NOT_FOUND"),
+		WithErrorObserver(func(c *gin.Context, event ErrorEvent) {
+			events = append(events, event)
+		}),
+	)
+	api.AddResource("/tasks", &failingResource{})
+
+	doRequestWithHeaders(engine, "GET", "/api/tasks/missing", map[string]string{RequestIDHeader: "req-1"})
+	doRequest(engine, "GET", "/api/tasks/other", "")
+	doRequest(engine, "GET", "/api/tasks", "")
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	e := events[0]
+	if e.Operation.Name != "Get" || e.Operation.ID != "missing" || e.Status != http.StatusNotFound || e.RequestID != "req-1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if !errors.Is(e.Err, errTaskNotFound) || !strings.HasPrefix(e.Err.Error(), "repo: load task") {
+		t.Errorf("expected the original error chain, got %v", e.Err)
+	}
+	if events[1].Status != http.StatusInternalServerError || events[1].Err.Error() != "unexpected" {
+		t.Errorf("unexpected event %+v", events[1])
+	}
+}
+
+func TestErrorObserver_MiddlewareErrors(t *testing.T) {
+	var got ErrorEvent
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithErrorObserver(func(c *gin.Context, event ErrorEvent) {
+		got = event
+	}))
+	api.Use(func(c *gin.Context) {
+		RenderError(c, Abort(http.StatusUnauthorized, "unauthorized"))
+	})
+	api.AddResource("/tasks", &failingResource{})
+
+	doRequest(engine, "GET", "/api/tasks/1", "")
+	if got.Status != http.StatusUnauthorized || got.Operation.Name != "" {
+		t.Errorf("expected an event without operation, got %+v", got)
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithCorrelationID(nil))
+	api.AddResource("/tasks", &failingResource{})
+
+	headers := map[string]string{RequestIDHeader: "req-42"}
+	tests := []struct {
+		id     string
+		status int
+		body   string
+	}{
+		{"http", http.StatusTeapot, `{"message":"teapot","correlation_id":"req-42"}`},
+		{"norows", http.StatusNotFound, `{"message":"not found","code":"NOT_FOUND","correlation_id":"req-42"}`},
+		{"other", http.StatusInternalServerError, `{"message":"internal server error","correlation_id":"req-42"}`},
+	}
+	for _, tt := range tests {
+		w := doRequestWithHeaders(engine, "GET", "/api/tasks/"+tt.id, headers)
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %s, got %d %s", tt.id, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	// Without a request ID the member is omitted.
+	if w := doRequest(engine, "GET", "/api/tasks/http", ""); w.Body.String() != `{"message":"teapot"}` {
+		t.Errorf("expected no correlation_id, got %s", w.Body.String())
+	}
+}
+
+func TestCorrelationID_DoesNotMutateSharedErrors(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithCorrelationID(func(c *gin.Context) string { return "trace-1" }))
+	api.AddResource("/items", &sharedErrorResource{})
+
+	w := doRequest(engine, "GET", "/api/items/1", "")
+	if !strings.Contains(w.Body.String(), `"correlation_id":"trace-1"`) {
+		t.Errorf("expected the trace ID in the body, got %s", w.Body.String())
+	}
+	if errShared.CorrelationID != "" {
+		t.Errorf("expected the shared error to be left untouched, got %q", errShared.CorrelationID)
+	}
+}
+
+func TestCorrelationID_JSONAPI(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithJSONAPI(), WithCorrelationID(nil))
+	api.AddResource("/tasks", &failingResource{})
+
+	w := doRequestWithHeaders(engine, "GET", "/api/tasks/http", map[string]string{RequestIDHeader: "req-7"})
+	if !strings.Contains(w.Body.String(), `"id":"req-7"`) {
+		t.Errorf("expected the error object id to be the correlation ID, got %s", w.Body.String())
+	}
+}
@@ -1,14 +1,20 @@
 package restful
 
+import "net/http"
+
 // HTTPError represents an HTTP error with a status code and message.
 // When returned from a handler, the framework automatically sends the
 // status code and a JSON body containing the message. The Status field
-// is excluded from JSON serialization as it is sent as the HTTP status code.
+// is excluded from JSON serialization as it is sent as the HTTP status code,
+// as is Header, which holds response headers such as WWW-Authenticate.
+// CorrelationID is filled in by the API when WithCorrelationID is used.
 type HTTPError struct {
-	Status  int    `json:"-"`
-	Message string `json:"message"`
-	Code    string `json:"code,omitempty"`
-	Details any    `json:"details,omitempty"`
+	Status        int         `json:"-"`
+	Message       string      `json:"message"`
+	Code          string      `json:"code,omitempty"`
+	Details       any         `json:"details,omitempty"`
+	CorrelationID string      `json:"correlation_id,omitempty"`
+	Header        http.Header `json:"-"`
 }
 
 // Error implements the error interface.
@@ -34,6 +40,18 @@ func WithDetails(details any) ErrorOption {
 	}
 }
 
+// WithHeader adds a response header sent along with the HTTPError, e.g. a
+// WWW-Authenticate challenge or Retry-After. It may be repeated to add
+// several values for the same header.
+func WithHeader(key, value string) ErrorOption {
+	return func(e *HTTPError) {
+		if e.Header == nil {
+			e.Header = make(http.Header)
+		}
+		e.Header.Add(key, value)
+	}
+}
+
 // Abort creates an HTTPError with the given status code and message.
 // Optional ErrorOption arguments can set Code and Details fields:
 //
@@ -2,7 +2,11 @@ package restful
 
 import (
 	"errors"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestAbort(t *testing.T) {
@@ -80,3 +84,19 @@ func TestAbort_BackwardsCompatible(t *testing.T) {
 		t.Errorf("expected nil details, got %v", err.Details)
 	}
 }
+
+func TestAbort_WithHeader(t *testing.T) {
+	engine := gin.New()
+	engine.GET("/", makeHandler(func(c *gin.Context) (any, int, error) {
+		return nil, 0, Abort(429, "slow down", WithHeader("Retry-After", "30"), WithHeader("Vary", "A"), WithHeader("Vary", "B"))
+	}))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get("Retry-After") != "30" || len(w.Header().Values("Vary")) != 2 {
+		t.Errorf("expected error headers on the response, got %v", w.Header())
+	}
+	if strings.Contains(w.Body.String(), "Retry-After") {
+		t.Errorf("headers should not be serialized, got %s", w.Body.String())
+	}
+}
@@ -162,6 +162,17 @@ func authMiddleware() gin.HandlerFunc {
 	}
 }
 
+// 관리자 전용 미들웨어 (X-Role: admin 헤더 확인)
+func adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Role") != "admin" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "admin only"})
+			return
+		}
+		c.Next()
+	}
+}
+
 func main() {
 	engine := gin.Default()
 	store := NewTaskStore()
@@ -170,16 +181,20 @@ func main() {
 	engine.GET("/health", healthHandler)
 	engine.GET("/stats", statsHandler(store))
 
-	// --- restful 리소스 (인증 미들웨어가 적용된 그룹) ---
-	authorized := engine.Group("/", authMiddleware())
-	api := restful.NewAPI(authorized, "/api/v1")
+	// --- restful 리소스 (API에 등록된 라우트에만 인증 미들웨어 적용) ---
+	api := restful.NewAPI(engine, "/api/v1")
+	api.Use(authMiddleware())
 
-	api.AddResource("/tasks", &TaskResource{store: store})
+	// 삭제는 관리자만 가능
+	api.AddResource("/tasks", &TaskResource{store: store},
+		restful.WithMethodMiddleware(http.MethodDelete, adminMiddleware()),
+	)
 
 	log.Println("Hybrid server running on :8080")
 	log.Println("  GET  /health          — no auth")
 	log.Println("  GET  /stats           — no auth")
 	log.Println("  *    /api/v1/tasks/** — requires X-User-ID header")
+	log.Println("  DELETE /api/v1/tasks/:id — also requires X-Role: admin")
 	if err := engine.Run(":8080"); err != nil {
 		log.Fatalln(err)
 	}
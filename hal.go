@@ -0,0 +1,194 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HALMediaType is the media type of HAL documents.
+const HALMediaType = "application/hal+json"
+
+// WithHAL adds HAL "_links" to rendered resource representations.
+//
+// Every item gets a "self" link plus links derived from the interfaces its
+// resource implements: "collection" (Lister), "update" (Putter), "patch"
+// (Patcher), "delete" (Deleter) and one per custom item action, plus one link
+// per resource registered below the item path (e.g. "/users/:id/posts" yields
+// a "posts" link on each user). Items are identified by their "id" JSON
+// member. Slice results from Lister are rendered as a collection page with
+// the items under "_embedded". Resources can add or remove links per item by
+// implementing Linker. WithHAL is ignored when WithJSONAPI is also set.
+func WithHAL() APIOption {
+	return func(api *API) {
+		api.hal = true
+	}
+}
+
+// Link is a hypermedia link rendered in a HAL "_links" object.
+type Link struct {
+	Href      string `json:"href"`
+	Method    string `json:"method,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+// Linker lets a resource customize the HAL links of each rendered item.
+// The returned links are merged over the generated ones; a link with an
+// empty Href removes the generated link of the same name, which allows
+// hiding actions the current user is not permitted to perform.
+type Linker interface {
+	Links(id string, item any, c *gin.Context) map[string]Link
+}
+
+// halHandler adapts fn to render HAL representations for the resource.
+func (api *API) halHandler(fn func(c *gin.Context) (any, int, error), entry *resourceEntry) func(c *gin.Context) (any, int, error) {
+	return func(c *gin.Context) (any, int, error) {
+		result, status, err := fn(c)
+		if err != nil || c.IsAborted() || status == http.StatusNoContent || result == nil {
+			return result, status, err
+		}
+
+		collection := c.FullPath() == entry.path && !entry.singleton
+		base := c.Request.URL.Path
+		if !collection && !entry.singleton {
+			base = path.Dir(base)
+		}
+
+		var rendered any
+		if collection && c.Request.Method == http.MethodGet {
+			rendered = api.halCollection(c, entry, base, result)
+		} else {
+			rendered = api.halItem(c, entry, base, result)
+		}
+		c.Header("Content-Type", HALMediaType)
+		return rendered, status, nil
+	}
+}
+
+func (api *API) halCollection(c *gin.Context, entry *resourceEntry, base string, result any) any {
+	links := map[string]Link{"self": {Href: c.Request.URL.RequestURI()}}
+	for _, rt := range entry.routes {
+		switch rt.Path {
+		case entry.path:
+			if rt.Method == http.MethodPost {
+				links["create"] = Link{Href: base, Method: http.MethodPost}
+			}
+		case entry.path + "/" + rt.Name:
+			links[rt.Name] = Link{Href: base + "/" + rt.Name, Method: rt.Method}
+		}
+	}
+
+	if v := reflect.Indirect(reflect.ValueOf(result)); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = api.halItem(c, entry, base, v.Index(i).Interface())
+		}
+		return gin.H{
+			"_links":    links,
+			"_embedded": gin.H{entry.name: items},
+		}
+	}
+
+	page, ok := toJSONObject(result)
+	if !ok {
+		return result
+	}
+	// Collection pages often wrap the items with paging metadata, e.g.
+	// {"tasks": [...], "total": 3}; decorate items found in array members.
+	// The arrays may be the handler's own, so decorated copies replace them.
+	for name, member := range page {
+		elems, ok := member.([]any)
+		if !ok {
+			continue
+		}
+		items := make([]any, len(elems))
+		for i, elem := range elems {
+			items[i] = api.halItem(c, entry, base, elem)
+		}
+		page[name] = items
+	}
+	page["_links"] = links
+	return page
+}
+
+// halItem decorates item with its links. base is the collection path of a
+// regular resource, or the path of a singleton.
+func (api *API) halItem(c *gin.Context, entry *resourceEntry, base string, item any) any {
+	obj, ok := toJSONObject(item)
+	if !ok {
+		return item
+	}
+
+	id, self, itemPath := "", base, entry.path
+	if !entry.singleton {
+		if raw, ok := obj["id"]; ok && raw != nil {
+			id = fmt.Sprint(raw)
+		}
+		if id == "" {
+			return obj
+		}
+		self = base + "/" + id
+		itemPath = entry.path + "/:id"
+	}
+
+	links := map[string]Link{"self": {Href: self}}
+	for _, rt := range entry.routes {
+		switch {
+		case rt.Path == itemPath && rt.Method == http.MethodPut:
+			links["update"] = Link{Href: self, Method: http.MethodPut}
+		case rt.Path == itemPath && rt.Method == http.MethodPatch:
+			links["patch"] = Link{Href: self, Method: http.MethodPatch}
+		case rt.Path == itemPath && rt.Method == http.MethodDelete:
+			links["delete"] = Link{Href: self, Method: http.MethodDelete}
+		case rt.Path == entry.path && rt.Name == "List":
+			links["collection"] = Link{Href: base}
+		case rt.Path == itemPath+"/"+rt.Name:
+			links[rt.Name] = Link{Href: self + "/" + rt.Name, Method: rt.Method}
+		}
+	}
+
+	for _, related := range api.resources {
+		if rest, ok := strings.CutPrefix(related.path, itemPath+"/"); ok && !strings.Contains(rest, "/") {
+			links[related.name] = Link{Href: self + "/" + rest}
+		}
+	}
+
+	if linker, ok := entry.resource.(Linker); ok {
+		for name, link := range linker.Links(id, item, c) {
+			if link.Href == "" {
+				delete(links, name)
+				continue
+			}
+			links[name] = link
+		}
+	}
+	obj["_links"] = links
+	return obj
+}
+
+// toJSONObject returns the JSON object representation of v, or false when v
+// does not encode to a JSON object.
+func toJSONObject(v any) (map[string]any, bool) {
+	if obj, ok := v.(map[string]any); ok {
+		return maps.Clone(obj), true
+	}
+	raw, err := json.Marshal(v)
+	if err != nil || len(raw) == 0 || raw[0] != '{' {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, false
+	}
+	return obj, true
+}
@@ -0,0 +1,199 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type halUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type halUserResource struct{}
+
+func (r *halUserResource) List(c *gin.Context) (any, int, error) {
+	return []halUser{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}}, http.StatusOK, nil
+}
+
+func (r *halUserResource) Get(id string, c *gin.Context) (any, int, error) {
+	return halUser{ID: 1, Name: "alice"}, http.StatusOK, nil
+}
+
+func (r *halUserResource) Post(c *gin.Context) (any, int, error) {
+	return halUser{ID: 3, Name: "carol"}, http.StatusCreated, nil
+}
+
+func (r *halUserResource) Delete(id string, c *gin.Context) (any, int, error) {
+	return nil, http.StatusNoContent, nil
+}
+
+func (r *halUserResource) Links(id string, item any, c *gin.Context) map[string]Link {
+	links := map[string]Link{"avatar": {Href: "/avatars/" + id}}
+	if c.GetHeader("X-Role") != "admin" {
+		links["delete"] = Link{}
+	}
+	return links
+}
+
+type halPostResource struct{}
+
+func (r *halPostResource) List(c *gin.Context) (any, int, error) {
+	return gin.H{"posts": []gin.H{{"id": 7, "title": "hi"}}, "total": 1}, http.StatusOK, nil
+}
+
+// halPageResource returns the same page on every call.
+type halPageResource struct {
+	page map[string]any
+}
+
+func (r *halPageResource) List(c *gin.Context) (any, int, error) {
+	return r.page, http.StatusOK, nil
+}
+
+// --- helpers ---
+
+func setupHALRouter() *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithHAL())
+	api.AddResource("/users", &halUserResource{})
+	api.AddResource("/users/:id/posts", &halPostResource{})
+	return engine
+}
+
+func decodeHAL(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return doc
+}
+
+func halHref(doc map[string]any, rel string) string {
+	links, _ := doc["_links"].(map[string]any)
+	link, _ := links[rel].(map[string]any)
+	href, _ := link["href"].(string)
+	return href
+}
+
+// --- tests ---
+
+func TestHAL_Get_AddsItemLinks(t *testing.T) {
+	engine := setupHALRouter()
+
+	w := doRequest(engine, "GET", "/api/users/1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != HALMediaType {
+		t.Errorf("expected HAL content type, got %q", ct)
+	}
+
+	doc := decodeHAL(t, w.Body.Bytes())
+	if doc["name"] != "alice" {
+		t.Errorf("expected representation fields to be kept, got %v", doc)
+	}
+	expected := map[string]string{
+		"self":       "/api/users/1",
+		"collection": "/api/users",
+		"posts":      "/api/users/1/posts",
+		"avatar":     "/avatars/1",
+	}
+	for rel, href := range expected {
+		if got := halHref(doc, rel); got != href {
+			t.Errorf("%s: expected %q, got %q", rel, href, got)
+		}
+	}
+	if halHref(doc, "update") != "" {
+		t.Error("update link should be absent when Putter is not implemented")
+	}
+	if halHref(doc, "delete") != "" {
+		t.Error("Linker should be able to remove the delete link")
+	}
+}
+
+func TestHAL_Get_LinkerKeepsPermittedActions(t *testing.T) {
+	engine := setupHALRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("X-Role", "admin")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	doc := decodeHAL(t, w.Body.Bytes())
+	if halHref(doc, "delete") != "/api/users/1" {
+		t.Errorf("expected delete link for admin, got %v", doc["_links"])
+	}
+}
+
+func TestHAL_List_EmbedsItems(t *testing.T) {
+	engine := setupHALRouter()
+
+	w := doRequest(engine, "GET", "/api/users", "")
+	doc := decodeHAL(t, w.Body.Bytes())
+	if halHref(doc, "self") != "/api/users" {
+		t.Errorf("expected collection self link, got %v", doc["_links"])
+	}
+	if halHref(doc, "create") != "/api/users" {
+		t.Errorf("expected create link, got %v", doc["_links"])
+	}
+
+	embedded, _ := doc["_embedded"].(map[string]any)
+	users, _ := embedded["users"].([]any)
+	if len(users) != 2 {
+		t.Fatalf("expected 2 embedded users, got %v", doc["_embedded"])
+	}
+	if href := halHref(users[1].(map[string]any), "self"); href != "/api/users/2" {
+		t.Errorf("expected item self link, got %q", href)
+	}
+}
+
+func TestHAL_List_DecoratesWrappedPage(t *testing.T) {
+	engine := setupHALRouter()
+
+	w := doRequest(engine, "GET", "/api/users/5/posts", "")
+	doc := decodeHAL(t, w.Body.Bytes())
+	if doc["total"] != float64(1) {
+		t.Errorf("expected page metadata to be kept, got %v", doc)
+	}
+	posts, _ := doc["posts"].([]any)
+	if len(posts) != 1 {
+		t.Fatalf("expected 1 post, got %v", doc["posts"])
+	}
+	if href := halHref(posts[0].(map[string]any), "self"); href != "/api/users/5/posts/7" {
+		t.Errorf("expected nested self link, got %q", href)
+	}
+}
+
+func TestHAL_List_LeavesResultUntouched(t *testing.T) {
+	items := []any{map[string]any{"id": 7}}
+	resource := &halPageResource{page: map[string]any{"posts": items}}
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithHAL())
+	api.AddResource("/posts", resource)
+
+	w := doRequest(engine, "GET", "/api/posts", "")
+	if href := halHref(decodeHAL(t, w.Body.Bytes())["posts"].([]any)[0].(map[string]any), "self"); href != "/api/posts/7" {
+		t.Errorf("expected the item to be decorated, got %q", href)
+	}
+	if _, ok := items[0].(map[string]any)["_links"]; ok || len(resource.page) != 1 {
+		t.Errorf("expected the resource's page to be left as is, got %v", resource.page)
+	}
+}
+
+func TestHAL_Post_AddsSelfLink(t *testing.T) {
+	engine := setupHALRouter()
+
+	w := doRequest(engine, "POST", "/api/users", `{}`)
+	doc := decodeHAL(t, w.Body.Bytes())
+	if halHref(doc, "self") != "/api/users/3" {
+		t.Errorf("expected self link for created item, got %v", doc["_links"])
+	}
+}
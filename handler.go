@@ -16,21 +16,57 @@ func makeHandlerWithErrorHandler(fn func(c *gin.Context) (any, int, error), errH
 	return func(c *gin.Context) {
 		result, status, err := fn(c)
 		if err != nil {
-			if errHandler != nil {
-				errHandler(c, err, status)
-			} else {
-				handleError(c, err, status)
-			}
+			renderError(c, err, status, errHandler)
 			return
 		}
 		if status == http.StatusNoContent {
 			c.Status(status)
 			return
 		}
+		if stream, ok := result.(Stream); ok {
+			writeStream(c, stream, status, errHandler)
+			return
+		}
 		c.JSON(status, result)
 	}
 }
 
+const errorHandlerKey = "gin-restful.error-handler"
+
+// RenderError responds with err the way a resource handler returning it
+// would, using the error handler of the API the route was registered
+// through, and aborts the request. It lets middleware such as
+// authentication report errors consistently with the resources it guards.
+func RenderError(c *gin.Context, err error) {
+	errHandler, _ := c.Value(errorHandlerKey).(ErrorHandlerFunc)
+	renderError(c, err, 0, errHandler)
+}
+
+// renderError sends the headers of an HTTPError err, then hands err to
+// errHandler, or to the default handler when errHandler is nil. The API's
+// error handler sends the headers of the HTTPError it maps other errors to.
+func renderError(c *gin.Context, err error, status int, errHandler ErrorHandlerFunc) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		addErrorHeaders(c, httpErr)
+	}
+	if errHandler != nil {
+		errHandler(c, err, status)
+	} else {
+		handleError(c, err, status)
+	}
+	c.Abort()
+}
+
+// addErrorHeaders adds the headers of httpErr to the response.
+func addErrorHeaders(c *gin.Context, httpErr *HTTPError) {
+	for key, values := range httpErr.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(key, value)
+		}
+	}
+}
+
 func handleError(c *gin.Context, err error, fallbackStatus int) {
 	var httpErr *HTTPError
 	if errors.As(err, &httpErr) {
@@ -54,3 +90,15 @@ func normalizePath(path string) string {
 	}
 	return path
 }
+
+// resourceName returns the last static segment of path, e.g. "posts" for
+// "/api/users/:id/posts".
+func resourceName(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if s := segments[i]; s != "" && s[0] != ':' && s[0] != '*' {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package restful
+
+import "github.com/gin-gonic/gin"
+
+const operationKey = "gin-restful.operation"
+
+// Operation describes the resource operation handling the current request.
+type Operation struct {
+	// Resource is the resource name: the last static segment of its path,
+	// e.g. "posts" for "/users/:id/posts".
+	Resource string
+	// Name is the handler method ("List", "Get", "Post", "Put", "Patch",
+	// "Delete", "BulkPost", "BulkPatch", "BulkDelete") or the name of a
+	// custom action.
+	Name string
+	// Method is the HTTP method.
+	Method string
+	// Route is the route template, e.g. "/api/posts/:id".
+	Route string
+	// ID is the resource id from the path. It is empty for collection
+	// routes and singletons.
+	ID string
+}
+
+// GetOperation returns the Operation of a request handled by a resource
+// registered through an API. It can be used from middleware attached with
+// API.Use, WithMiddleware or WithMethodMiddleware once the handler has
+// started, and from inside handlers.
+func GetOperation(c *gin.Context) (Operation, bool) {
+	op, ok := c.Get(operationKey)
+	if !ok {
+		return Operation{}, false
+	}
+	return op.(Operation), true
+}
+
+// BeforeHandler is implemented by resources that run logic before each of
+// their handlers. Returning an error skips the handler and responds with the
+// error.
+type BeforeHandler interface {
+	BeforeHandler(op Operation, c *gin.Context) error
+}
+
+// AfterHandler is implemented by resources that post-process the result of
+// each of their successful handlers. It may replace the result and status or
+// return an error to respond with instead.
+type AfterHandler interface {
+	AfterHandler(op Operation, result any, status int, c *gin.Context) (any, int, error)
+}
+
+// BeforeHookFunc is an API-wide hook with the semantics of BeforeHandler.
+type BeforeHookFunc func(op Operation, c *gin.Context) error
+
+// AfterHookFunc is an API-wide hook with the semantics of AfterHandler.
+type AfterHookFunc func(op Operation, result any, status int, c *gin.Context) (any, int, error)
+
+// WithBeforeHook registers a hook that runs before every resource handler of
+// the API, ahead of the resource's own BeforeHandler. Hooks run in
+// registration order.
+func WithBeforeHook(hook BeforeHookFunc) APIOption {
+	return func(api *API) {
+		api.beforeHooks = append(api.beforeHooks, hook)
+	}
+}
+
+// WithAfterHook registers a hook that runs after every successful resource
+// handler of the API, following the resource's own AfterHandler. Hooks run
+// in registration order.
+func WithAfterHook(hook AfterHookFunc) APIOption {
+	return func(api *API) {
+		api.afterHooks = append(api.afterHooks, hook)
+	}
+}
+
+// withHooks runs the API-wide and resource hooks around fn.
+func (api *API) withHooks(entry *resourceEntry, fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	before, hasBefore := entry.resource.(BeforeHandler)
+	after, hasAfter := entry.resource.(AfterHandler)
+	if !hasBefore && !hasAfter && len(api.beforeHooks) == 0 && len(api.afterHooks) == 0 {
+		return fn
+	}
+
+	return func(c *gin.Context) (any, int, error) {
+		op, _ := GetOperation(c)
+		for _, hook := range api.beforeHooks {
+			if err := hook(op, c); err != nil {
+				return nil, 0, err
+			}
+		}
+		if hasBefore {
+			if err := before.BeforeHandler(op, c); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		result, status, err := fn(c)
+		if err != nil || c.IsAborted() {
+			return result, status, err
+		}
+
+		if hasAfter {
+			if result, status, err = after.AfterHandler(op, result, status, c); err != nil {
+				return nil, status, err
+			}
+		}
+		for _, hook := range api.afterHooks {
+			if result, status, err = hook(op, result, status, c); err != nil {
+				return nil, status, err
+			}
+		}
+		return result, status, nil
+	}
+}
@@ -0,0 +1,161 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type hookedResource struct {
+	ops []Operation
+}
+
+func (r *hookedResource) List(c *gin.Context) (any, int, error) {
+	return []string{"a"}, http.StatusOK, nil
+}
+
+func (r *hookedResource) Get(id string, c *gin.Context) (any, int, error) {
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+func (r *hookedResource) Delete(id string, c *gin.Context) (any, int, error) {
+	return nil, 0, Abort(http.StatusConflict, "locked")
+}
+
+func (r *hookedResource) BeforeHandler(op Operation, c *gin.Context) error {
+	r.ops = append(r.ops, op)
+	if c.GetHeader("X-Tenant") == "" {
+		return Abort(http.StatusBadRequest, "tenant required")
+	}
+	return nil
+}
+
+func (r *hookedResource) AfterHandler(op Operation, result any, status int, c *gin.Context) (any, int, error) {
+	return gin.H{"data": result, "op": op.Name}, status, nil
+}
+
+// --- tests ---
+
+func TestHooks_ResourceBeforeAndAfter(t *testing.T) {
+	resource := &hookedResource{}
+	engine := setupRouter("/items", resource)
+
+	req := doRequestWithHeaders(engine, "GET", "/api/items/7", map[string]string{"X-Tenant": "acme"})
+	if req.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", req.Code)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(req.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp["op"] != "Get" {
+		t.Errorf("expected AfterHandler to decorate the result, got %v", resp)
+	}
+
+	want := Operation{Resource: "items", Name: "Get", Method: http.MethodGet, Route: "/api/items/:id", ID: "7"}
+	if len(resource.ops) != 1 || resource.ops[0] != want {
+		t.Errorf("expected operation %+v, got %+v", want, resource.ops)
+	}
+}
+
+func TestHooks_BeforeErrorSkipsHandler(t *testing.T) {
+	resource := &hookedResource{}
+	engine := setupRouter("/items", resource)
+
+	w := doRequest(engine, "GET", "/api/items", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 from BeforeHandler, got %d", w.Code)
+	}
+	if resource.ops[0].ID != "" || resource.ops[0].Name != "List" {
+		t.Errorf("unexpected collection operation: %+v", resource.ops[0])
+	}
+}
+
+func TestHooks_AfterNotCalledOnError(t *testing.T) {
+	engine := setupRouter("/items", &hookedResource{})
+
+	w := doRequestWithHeaders(engine, "DELETE", "/api/items/1", map[string]string{"X-Tenant": "acme"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected handler error to pass through, got %d", w.Code)
+	}
+}
+
+func TestHooks_APIWideOrder(t *testing.T) {
+	var trace []string
+	engine := gin.New()
+	api := NewAPI(engine, "/api",
+		WithBeforeHook(func(op Operation, c *gin.Context) error {
+			trace = append(trace, "before:"+op.Name)
+			return nil
+		}),
+		WithAfterHook(func(op Operation, result any, status int, c *gin.Context) (any, int, error) {
+			trace = append(trace, "after:"+op.Name)
+			return result, http.StatusAccepted, nil
+		}),
+	)
+	api.AddResource("/items", &fullCRUDResource{})
+
+	w := doRequest(engine, "PUT", "/api/items/1", `{}`)
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected after hook to replace the status, got %d", w.Code)
+	}
+	if len(trace) != 2 || trace[0] != "before:Put" || trace[1] != "after:Put" {
+		t.Errorf("unexpected hook trace: %v", trace)
+	}
+}
+
+func TestHooks_APIWideBeforeError(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithBeforeHook(func(op Operation, c *gin.Context) error {
+		if op.Name == "Delete" {
+			return Abort(http.StatusForbidden, "read only")
+		}
+		return nil
+	}))
+	api.AddResource("/items", &fullCRUDResource{})
+
+	if w := doRequest(engine, "DELETE", "/api/items/1", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
+	}
+	if w := doRequest(engine, "GET", "/api/items/1", ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestGetOperation_ActionsAndMiddleware(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+
+	var seen Operation
+	api.Use(func(c *gin.Context) {
+		c.Next()
+		seen, _ = GetOperation(c)
+	})
+	api.AddResource("/orders", &orderResource{})
+
+	doRequest(engine, "POST", "/api/orders/9/cancel", "")
+	want := Operation{Resource: "orders", Name: "cancel", Method: http.MethodPost, Route: "/api/orders/:id/cancel", ID: "9"}
+	if seen != want {
+		t.Errorf("expected %+v, got %+v", want, seen)
+	}
+}
+
+func TestGetOperation_BulkPostOnSharedRoute(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithBeforeHook(func(op Operation, c *gin.Context) error {
+		c.Header("X-Operation", op.Name)
+		return nil
+	}))
+	api.AddResource("/items", &bulkResource{})
+
+	if w := doRequest(engine, "POST", "/api/items", `[{"name":"a"}]`); w.Header().Get("X-Operation") != "BulkPost" {
+		t.Errorf("expected BulkPost, got %q", w.Header().Get("X-Operation"))
+	}
+	if w := doRequest(engine, "POST", "/api/items", `{"name":"a"}`); w.Header().Get("X-Operation") != "Post" {
+		t.Errorf("expected Post, got %q", w.Header().Get("X-Operation"))
+	}
+}
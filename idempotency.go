@@ -0,0 +1,340 @@
+package restful
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header carrying the idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	// DefaultIdempotencyTTL is how long responses are kept for replay unless
+	// WithIdempotencyTTL is used.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyCapacity is the number of keys kept by the in-memory
+	// store used unless WithIdempotencyStore is given.
+	DefaultIdempotencyCapacity = 10000
+
+	maxIdempotencyKeyLength = 255
+	idempotencyPollInterval = 50 * time.Millisecond
+)
+
+// IdempotencyRecord is the state of an idempotency key: reserved by a
+// request in flight, or holding the response to replay once Completed.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request body the key was first used with.
+	Fingerprint string
+	Completed   bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore keeps the responses of requests sent with an
+// Idempotency-Key header. Keys passed to the store are already scoped to
+// the route and the principal of the request. Implementations must be safe
+// for concurrent use, and Reserve must be atomic across all instances
+// sharing the store.
+type IdempotencyStore interface {
+	// Reserve claims key for a new request with the given fingerprint and
+	// returns true, or returns the existing record of the key and false.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	// Complete stores the response of a request that reserved key.
+	Complete(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	// Release forgets key, allowing it to be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOption configures WithIdempotency.
+type IdempotencyOption func(*idempotencyConfig)
+
+type idempotencyConfig struct {
+	store IdempotencyStore
+	ttl   time.Duration
+	wait  time.Duration
+}
+
+// WithIdempotencyStore sets the store for idempotency keys, e.g. one backed
+// by Redis when the API runs on several instances. The default is an
+// in-memory MemoryIdempotencyStore.
+func WithIdempotencyStore(store IdempotencyStore) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.store = store
+	}
+}
+
+// WithIdempotencyTTL sets how long a key and its response are kept.
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithIdempotencyWait makes a request whose key is still in flight wait up
+// to d for the first request to complete and then replay its response.
+// By default such requests get 409 Conflict immediately.
+func WithIdempotencyWait(d time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.wait = d
+	}
+}
+
+// WithIdempotency honors the Idempotency-Key header on the resource's Post,
+// BulkPost, BulkPatch and BulkDelete routes. The first response for a key
+// (status, headers and body) is stored and replayed to retries with an
+// "Idempotent-Replayed: true" header. A key reused with a different request
+// body gets 422, and a retry arriving while the first request is still
+// running gets 409 unless WithIdempotencyWait is used. Responses with a 5xx
+// status, error responses to requests canceled while running and requests
+// whose handler panicked are not stored, so such requests can be retried.
+// Keys are scoped to the request path and the principal set with
+// SetPrincipal.
+func WithIdempotency(opts ...IdempotencyOption) ResourceOption {
+	cfg := &idempotencyConfig{ttl: DefaultIdempotencyTTL}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryIdempotencyStore(DefaultIdempotencyCapacity)
+	}
+	return func(rc *resourceConfig) {
+		rc.idempotency = cfg
+	}
+}
+
+// idempotentOperations are the operations WithIdempotency applies to.
+var idempotentOperations = []string{"Post", "BulkPost", "BulkPatch", "BulkDelete"}
+
+// handler is the middleware enforcing idempotency keys on a route.
+func (cfg *idempotencyConfig) handler(c *gin.Context) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		RenderError(c, Abort(http.StatusBadRequest, "idempotency key is too long", WithCode("IDEMPOTENCY_KEY_INVALID")))
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		RenderError(c, Abort(http.StatusBadRequest, "failed to read request body"))
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	subject := ""
+	if p, ok := PrincipalFrom(c); ok {
+		subject = p.Subject
+	}
+	storeKey := strings.Join([]string{c.Request.Method, c.Request.URL.Path, subject, key}, "\x00")
+
+	ctx := c.Request.Context()
+	deadline := time.Now().Add(cfg.wait)
+	for {
+		record, reserved, err := cfg.store.Reserve(ctx, storeKey, fingerprint, cfg.ttl)
+		if err != nil {
+			RenderError(c, err)
+			return
+		}
+		if reserved {
+			break
+		}
+		if record.Fingerprint != fingerprint {
+			RenderError(c, Abort(http.StatusUnprocessableEntity,
+				"idempotency key was already used with a different request body",
+				WithCode("IDEMPOTENCY_KEY_REUSED")))
+			return
+		}
+		if record.Completed {
+			replayIdempotent(c, record)
+			return
+		}
+		if !time.Now().Before(deadline) {
+			RenderError(c, Abort(http.StatusConflict,
+				"a request with this idempotency key is already in progress",
+				WithCode("IDEMPOTENCY_KEY_IN_USE")))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			RenderError(c, ctx.Err())
+			return
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+
+	// The response is stored even when the client went away, so that its
+	// retry gets the outcome instead of running the operation again.
+	storeCtx := context.WithoutCancel(ctx)
+	capture := &captureWriter{ResponseWriter: c.Writer}
+	c.Writer = capture
+	defer func() {
+		c.Writer = capture.ResponseWriter
+		// A panic left to gin's recovery must not keep the key reserved.
+		if v := recover(); v != nil {
+			_ = cfg.store.Release(storeCtx, storeKey)
+			panic(v)
+		}
+	}()
+	c.Next()
+
+	// Errors of canceled requests, such as 499, are likely caused by the
+	// cancellation rather than the request, so retries run again.
+	status := capture.Status()
+	canceled := status >= http.StatusBadRequest && ctx.Err() != nil
+	if status >= http.StatusInternalServerError || status == StatusClientClosedRequest || canceled || capture.streamed {
+		_ = cfg.store.Release(storeCtx, storeKey)
+		return
+	}
+	_ = cfg.store.Complete(storeCtx, storeKey, IdempotencyRecord{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		Status:      status,
+		Header:      withoutPerRequestHeaders(capture.Header()),
+		Body:        capture.body.Bytes(),
+	}, cfg.ttl)
+}
+
+func replayIdempotent(c *gin.Context, record *IdempotencyRecord) {
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = slices.Clone(values)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// captureWriter keeps a copy of the response body written through it,
+// unless the response is streamed.
+type captureWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	streamed bool
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if !w.streamed {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	if !w.streamed {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// startStream implements streamer.
+func (w *captureWriter) startStream() bool {
+	if s, ok := w.ResponseWriter.(streamer); ok && !s.startStream() {
+		return false
+	}
+	w.streamed = true
+	w.body.Reset()
+	return true
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore that evicts the
+// least recently used keys beyond its capacity, as well as expired ones.
+// It only deduplicates requests served by the same process.
+type MemoryIdempotencyStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // of *memoryIdempotencyEntry, most recently used first
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+type memoryIdempotencyEntry struct {
+	key     string
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns a MemoryIdempotencyStore holding up to
+// capacity keys, or any number of keys when capacity is zero.
+func NewMemoryIdempotencyStore(capacity int) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Reserve implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Reserve(_ context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if elem, ok := s.entries[key]; ok {
+		entry := elem.Value.(*memoryIdempotencyEntry)
+		if now.Before(entry.expires) {
+			s.order.MoveToFront(elem)
+			record := entry.record
+			return &record, false, nil
+		}
+		s.remove(elem)
+	}
+
+	entry := &memoryIdempotencyEntry{
+		key:     key,
+		record:  IdempotencyRecord{Fingerprint: fingerprint},
+		expires: now.Add(ttl),
+	}
+	s.entries[key] = s.order.PushFront(entry)
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return nil, true, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		entry := elem.Value.(*memoryIdempotencyEntry)
+		entry.record = record
+		entry.expires = s.now().Add(ttl)
+		s.order.MoveToFront(elem)
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*memoryIdempotencyEntry).key)
+}
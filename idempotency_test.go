@@ -0,0 +1,299 @@
+package restful
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type orderCounterResource struct {
+	mu      sync.Mutex
+	created int
+	fail    bool
+	release chan struct{}
+}
+
+func (r *orderCounterResource) Post(c *gin.Context) (any, int, error) {
+	if r.release != nil {
+		<-r.release
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return nil, 0, Abort(http.StatusServiceUnavailable, "try again")
+	}
+	r.created++
+	c.Header("Location", "/api/orders/1")
+	return gin.H{"id": r.created}, http.StatusCreated, nil
+}
+
+func (r *orderCounterResource) Get(id string, c *gin.Context) (any, int, error) {
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+// flakyOrderResource panics or fails with its request's context error
+// while its mode is set.
+type flakyOrderResource struct {
+	mode string
+}
+
+func (r *flakyOrderResource) Post(c *gin.Context) (any, int, error) {
+	switch r.mode {
+	case "panic":
+		panic("driver bug")
+	case "cancel":
+		return nil, 0, c.Request.Context().Err()
+	}
+	return gin.H{"id": 1}, http.StatusCreated, nil
+}
+
+// --- helpers ---
+
+func setupIdempotencyRouter(resource any, opts ...IdempotencyOption) *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			SetPrincipal(c, &Principal{Subject: user})
+		}
+	})
+	api.AddResource("/orders", resource, WithIdempotency(opts...))
+	return engine
+}
+
+func doIdempotentRequest(engine *gin.Engine, key, body, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// --- tests ---
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	resource := &orderCounterResource{}
+	engine := setupIdempotencyRouter(resource)
+
+	first := doIdempotentRequest(engine, "k1", `{"item":"book"}`, "")
+	second := doIdempotentRequest(engine, "k1", `{"item":"book"}`, "")
+
+	if resource.created != 1 {
+		t.Fatalf("expected the order to be created once, got %d", resource.created)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("expected replay of %d %s, got %d %s", first.Code, first.Body.String(), second.Code, second.Body.String())
+	}
+	if second.Header().Get("Location") != "/api/orders/1" || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected stored headers on replay, got %v", second.Header())
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("first response should not be marked as replayed")
+	}
+}
+
+func TestIdempotency_ReplayKeepsPerRequestHeaders(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRequestID(nil))
+	api.AddResource("/orders", &orderCounterResource{},
+		WithIdempotency(), WithRateLimit(RateLimit{Limit: 10, Window: time.Minute}))
+
+	first := doIdempotentRequest(engine, "k1", `{}`, "")
+	second := doIdempotentRequest(engine, "k1", `{}`, "")
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected a replay, got %v", second.Header())
+	}
+	if id := second.Header().Get(RequestIDHeader); id == "" || id == first.Header().Get(RequestIDHeader) {
+		t.Errorf("expected a new request ID, got %q", id)
+	}
+	if second.Header().Get("RateLimit-Remaining") != "8" {
+		t.Errorf("expected the current rate limit, got %q", second.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	resource := &orderCounterResource{}
+	engine := setupIdempotencyRouter(resource)
+
+	doIdempotentRequest(engine, "", `{}`, "")
+	doIdempotentRequest(engine, "", `{}`, "")
+	if resource.created != 2 {
+		t.Errorf("expected requests without a key to run every time, got %d", resource.created)
+	}
+}
+
+func TestIdempotency_DifferentBody_Returns422(t *testing.T) {
+	engine := setupIdempotencyRouter(&orderCounterResource{})
+
+	doIdempotentRequest(engine, "k1", `{"item":"book"}`, "")
+	w := doIdempotentRequest(engine, "k1", `{"item":"pen"}`, "")
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "IDEMPOTENCY_KEY_REUSED") {
+		t.Errorf("expected 422, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotency_ScopedToPrincipal(t *testing.T) {
+	resource := &orderCounterResource{}
+	engine := setupIdempotencyRouter(resource)
+
+	doIdempotentRequest(engine, "k1", `{}`, "alice")
+	w := doIdempotentRequest(engine, "k1", `{}`, "bob")
+	if resource.created != 2 || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected keys of different principals to be independent, created %d", resource.created)
+	}
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	resource := &orderCounterResource{fail: true}
+	engine := setupIdempotencyRouter(resource)
+
+	if w := doIdempotentRequest(engine, "k1", `{}`, ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	resource.fail = false
+	if w := doIdempotentRequest(engine, "k1", `{}`, ""); w.Code != http.StatusCreated {
+		t.Errorf("expected the retry to run the handler, got %d", w.Code)
+	}
+}
+
+func TestIdempotency_PanicsReleaseKey(t *testing.T) {
+	resource := &flakyOrderResource{mode: "panic"}
+	engine := gin.New()
+	engine.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	api := NewAPI(engine, "/api")
+	api.AddResource("/orders", resource, WithIdempotency())
+
+	if w := doIdempotentRequest(engine, "k1", `{}`, ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	resource.mode = ""
+	if w := doIdempotentRequest(engine, "k1", `{}`, ""); w.Code != http.StatusCreated {
+		t.Errorf("expected the retry to run the handler, got %d", w.Code)
+	}
+}
+
+func TestIdempotency_CanceledRequestsAreNotStored(t *testing.T) {
+	resource := &flakyOrderResource{mode: "cancel"}
+	engine := setupIdempotencyRouter(resource)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/api/orders", strings.NewReader(`{}`)).WithContext(ctx)
+	req.Header.Set(IdempotencyKeyHeader, "k1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != StatusClientClosedRequest {
+		t.Fatalf("expected 499, got %d", w.Code)
+	}
+	resource.mode = ""
+	if w := doIdempotentRequest(engine, "k1", `{}`, ""); w.Code != http.StatusCreated {
+		t.Errorf("expected the retry to run the handler, got %d", w.Code)
+	}
+}
+
+func TestIdempotency_ConcurrentDuplicate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []IdempotencyOption
+		want int
+	}{
+		{"conflict", nil, http.StatusConflict},
+		{"wait", []IdempotencyOption{WithIdempotencyWait(time.Second)}, http.StatusCreated},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &orderCounterResource{release: make(chan struct{})}
+			engine := setupIdempotencyRouter(resource, tt.opts...)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				doIdempotentRequest(engine, "k1", `{}`, "")
+			}()
+			// Wait for the first request to reserve the key.
+			time.Sleep(20 * time.Millisecond)
+
+			var w *httptest.ResponseRecorder
+			duplicate := make(chan struct{})
+			go func() {
+				defer close(duplicate)
+				w = doIdempotentRequest(engine, "k1", `{}`, "")
+			}()
+			time.Sleep(20 * time.Millisecond)
+			close(resource.release)
+			<-done
+			<-duplicate
+
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, w.Code)
+			}
+			if resource.created != 1 {
+				t.Errorf("expected one order, got %d", resource.created)
+			}
+		})
+	}
+}
+
+func TestIdempotency_BulkRoutesOnly(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/items", &bulkResource{}, WithIdempotency())
+	api.AddResource("/orders", &orderCounterResource{}, WithIdempotency())
+
+	doBulk := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/api/items", strings.NewReader(`["1","missing"]`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	doBulk()
+	if w := doBulk(); w.Code != http.StatusMultiStatus || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected bulk delete to be replayed, got %d %v", w.Code, w.Header())
+	}
+
+	headers := map[string]string{IdempotencyKeyHeader: "k2"}
+	doRequestWithHeaders(engine, "GET", "/api/orders/1", headers)
+	if w := doRequestWithHeaders(engine, "GET", "/api/orders/1", headers); w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("GET requests should not be replayed")
+	}
+}
+
+func TestMemoryIdempotencyStore_EvictsAndExpires(t *testing.T) {
+	store := NewMemoryIdempotencyStore(2)
+	now := time.Unix(0, 0)
+	store.now = func() time.Time { return now }
+	ctx := t.Context()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok, _ := store.Reserve(ctx, key, "f", time.Minute); !ok {
+			t.Fatalf("expected %s to be reserved", key)
+		}
+	}
+	if _, ok, _ := store.Reserve(ctx, "a", "f", time.Minute); !ok {
+		t.Error("expected the least recently used key to be evicted")
+	}
+	if record, ok, _ := store.Reserve(ctx, "c", "f", time.Minute); ok || record.Completed {
+		t.Error("expected c to still be in flight")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := store.Reserve(ctx, "c", "f", time.Minute); !ok {
+		t.Error("expected the expired key to be reserved again")
+	}
+}
@@ -0,0 +1,451 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSONAPIMediaType is the media type of JSON:API documents.
+const JSONAPIMediaType = "application/vnd.api+json"
+
+// WithJSONAPI renders resource results as JSON:API documents.
+//
+// Result types describe themselves with `jsonapi` struct tags:
+//
+//	type Article struct {
+//	    ID     string  `jsonapi:"primary,articles"`
+//	    Title  string  `jsonapi:"attr,title"`
+//	    Views  int     `jsonapi:"meta,views"`
+//	    Author *Person `jsonapi:"relation,author"`
+//	}
+//
+// Only tagged fields are serialized; a trailing "omitempty" option skips
+// zero values. Related resources are added to the top-level "included"
+// member, restricted to the relationships named in the "include" query
+// parameter when it is present. Wrap a result in JSONAPIResult to add
+// top-level meta or links.
+//
+// Request bodies sent to Poster and Patcher with the JSON:API media type are
+// flattened before the handler runs: attributes become top-level members, the
+// resource id becomes "id" and relationships become ids (or id arrays), so
+// Bind[T] works with plain `json` tags. Errors are rendered as a JSON:API
+// "errors" array unless WithErrorHandler is also set.
+func WithJSONAPI() APIOption {
+	return func(api *API) {
+		api.jsonapi = true
+	}
+}
+
+// JSONAPIResult wraps a resource result with top-level document members.
+type JSONAPIResult struct {
+	Data  any
+	Meta  map[string]any
+	Links map[string]string
+}
+
+type jsonapiDocument struct {
+	Data     any                `json:"data"`
+	Included []*jsonapiResource `json:"included,omitempty"`
+	Links    map[string]string  `json:"links,omitempty"`
+	Meta     map[string]any     `json:"meta,omitempty"`
+}
+
+type jsonapiResource struct {
+	Type          string                          `json:"type"`
+	ID            string                          `json:"id"`
+	Attributes    map[string]any                  `json:"attributes,omitempty"`
+	Relationships map[string]*jsonapiRelationship `json:"relationships,omitempty"`
+	Links         map[string]string               `json:"links,omitempty"`
+	Meta          map[string]any                  `json:"meta,omitempty"`
+}
+
+type jsonapiIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type jsonapiRelationship struct {
+	Data any `json:"data"`
+}
+
+type jsonapiError struct {
+	ID     string         `json:"id,omitempty"`
+	Status string         `json:"status"`
+	Code   string         `json:"code,omitempty"`
+	Title  string         `json:"title"`
+	Detail string         `json:"detail,omitempty"`
+	Meta   map[string]any `json:"meta,omitempty"`
+}
+
+// jsonapiHandler adapts fn to read and write JSON:API documents for the
+// resource described by entry.
+func jsonapiHandler(fn func(c *gin.Context) (any, int, error), entry *resourceEntry) func(c *gin.Context) (any, int, error) {
+	return func(c *gin.Context) (any, int, error) {
+		if c.Request.Method == http.MethodPost || c.Request.Method == http.MethodPatch {
+			if err := flattenJSONAPIRequest(c, !entry.singleton); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		result, status, err := fn(c)
+		if err != nil || c.IsAborted() || status == http.StatusNoContent {
+			return result, status, err
+		}
+
+		selfLink := func(id string) string { return c.Request.URL.Path + "/" + id }
+		switch {
+		case entry.singleton:
+			selfLink = func(string) string { return c.Request.URL.Path }
+		case c.FullPath() != entry.path:
+			base := path.Dir(c.Request.URL.Path)
+			selfLink = func(id string) string { return base + "/" + id }
+		}
+
+		doc, err := newJSONAPIDocument(c, result, selfLink)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		c.Header("Content-Type", JSONAPIMediaType)
+		return doc, status, nil
+	}
+}
+
+// flattenJSONAPIRequest rewrites a JSON:API request document into a plain
+// JSON object. When checkID is set, the document id must match the :id
+// path parameter.
+func flattenJSONAPIRequest(c *gin.Context, checkID bool) error {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), JSONAPIMediaType) {
+		return nil
+	}
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return Abort(http.StatusBadRequest, "failed to read request body")
+	}
+
+	var doc struct {
+		Data *struct {
+			Type          string                     `json:"type"`
+			ID            string                     `json:"id"`
+			Attributes    map[string]json.RawMessage `json:"attributes"`
+			Relationships map[string]struct {
+				Data json.RawMessage `json:"data"`
+			} `json:"relationships"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return Abort(http.StatusBadRequest, "malformed JSON:API document")
+	}
+	if doc.Data == nil || doc.Data.Type == "" {
+		return Abort(http.StatusBadRequest, "JSON:API document requires a primary data object with a type")
+	}
+	if id := c.Param("id"); checkID && id != "" && doc.Data.ID != id {
+		return Abort(http.StatusConflict, "resource id does not match the URL")
+	}
+
+	flat := make(map[string]json.RawMessage, len(doc.Data.Attributes)+len(doc.Data.Relationships)+1)
+	for name, value := range doc.Data.Attributes {
+		flat[name] = value
+	}
+	for name, rel := range doc.Data.Relationships {
+		ids, err := jsonapiLinkageIDs(rel.Data)
+		if err != nil {
+			return Abort(http.StatusBadRequest, fmt.Sprintf("malformed relationship %q", name))
+		}
+		flat[name] = ids
+	}
+	if doc.Data.ID != "" {
+		flat["id"], _ = json.Marshal(doc.Data.ID)
+	}
+
+	body, _ := json.Marshal(flat)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	c.Request.Header.Set("Content-Type", gin.MIMEJSON)
+	return nil
+}
+
+// jsonapiLinkageIDs converts relationship linkage into an id, an id array or null.
+func jsonapiLinkageIDs(data json.RawMessage) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return json.RawMessage("null"), nil
+	}
+	if trimmed[0] == '[' {
+		var many []jsonapiIdentifier
+		if err := json.Unmarshal(trimmed, &many); err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(many))
+		for i, ident := range many {
+			ids[i] = ident.ID
+		}
+		return json.Marshal(ids)
+	}
+	var one jsonapiIdentifier
+	if err := json.Unmarshal(trimmed, &one); err != nil {
+		return nil, err
+	}
+	return json.Marshal(one.ID)
+}
+
+// jsonapiBuilder accumulates included resources while a document is built.
+type jsonapiBuilder struct {
+	selfLink func(id string) string
+	include  map[string]bool
+	primary  map[jsonapiIdentifier]bool
+	included []*jsonapiResource
+	seen     map[jsonapiIdentifier]bool
+	// visiting holds the resources being built, so that back-references
+	// such as an article's author listing the article end the recursion.
+	visiting map[jsonapiIdentifier]bool
+}
+
+func newJSONAPIDocument(c *gin.Context, result any, selfLink func(id string) string) (*jsonapiDocument, error) {
+	doc := &jsonapiDocument{Links: map[string]string{"self": c.Request.URL.RequestURI()}}
+	if wrapped, ok := result.(*JSONAPIResult); ok && wrapped != nil {
+		result = *wrapped
+	}
+	if wrapped, ok := result.(JSONAPIResult); ok {
+		result = wrapped.Data
+		doc.Meta = wrapped.Meta
+		for name, href := range wrapped.Links {
+			doc.Links[name] = href
+		}
+	}
+
+	b := &jsonapiBuilder{
+		selfLink: selfLink,
+		primary:  make(map[jsonapiIdentifier]bool),
+		seen:     make(map[jsonapiIdentifier]bool),
+		visiting: make(map[jsonapiIdentifier]bool),
+	}
+	if include := c.Query("include"); include != "" {
+		b.include = make(map[string]bool)
+		for _, name := range strings.Split(include, ",") {
+			b.include[strings.TrimSpace(name)] = true
+		}
+	}
+
+	v := reflect.ValueOf(result)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return doc, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return doc, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		data := make([]*jsonapiResource, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res, err := b.resource(v.Index(i), true)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, res)
+		}
+		doc.Data = data
+	default:
+		res, err := b.resource(v, true)
+		if err != nil {
+			return nil, err
+		}
+		doc.Data = res
+	}
+
+	// A compound document must not repeat primary data in "included".
+	for _, res := range b.included {
+		if !b.primary[jsonapiIdentifier{Type: res.Type, ID: res.ID}] {
+			doc.Included = append(doc.Included, res)
+		}
+	}
+	return doc, nil
+}
+
+func (b *jsonapiBuilder) resource(v reflect.Value, primary bool) (*jsonapiResource, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("gin-restful: %s is not a JSON:API resource", v.Type())
+	}
+
+	res := &jsonapiResource{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if tag, ok := t.Field(i).Tag.Lookup("jsonapi"); ok && t.Field(i).IsExported() {
+			if kind, name, _ := strings.Cut(tag, ","); kind == "primary" {
+				res.Type, _, _ = strings.Cut(name, ",")
+				res.ID = jsonapiFormatID(v.Field(i))
+			}
+		}
+	}
+	if res.Type == "" {
+		return nil, fmt.Errorf("gin-restful: %s has no `jsonapi:\"primary,<type>\"` field", t)
+	}
+
+	// A resource referring back to one being built is only linked to.
+	key := jsonapiIdentifier{Type: res.Type, ID: res.ID}
+	if b.visiting[key] {
+		return res, nil
+	}
+	b.visiting[key] = true
+	defer delete(b.visiting, key)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("jsonapi")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		kind := parts[0]
+		name := ""
+		if len(parts) > 1 {
+			name = parts[1]
+		}
+		omitEmpty := len(parts) > 2 && parts[2] == "omitempty"
+		value := v.Field(i)
+
+		switch kind {
+		case "attr":
+			if omitEmpty && value.IsZero() {
+				continue
+			}
+			if res.Attributes == nil {
+				res.Attributes = make(map[string]any)
+			}
+			res.Attributes[name] = value.Interface()
+		case "meta":
+			if omitEmpty && value.IsZero() {
+				continue
+			}
+			if res.Meta == nil {
+				res.Meta = make(map[string]any)
+			}
+			res.Meta[name] = value.Interface()
+		case "relation":
+			if omitEmpty && value.IsZero() {
+				continue
+			}
+			rel, err := b.relationship(name, value)
+			if err != nil {
+				return nil, err
+			}
+			if res.Relationships == nil {
+				res.Relationships = make(map[string]*jsonapiRelationship)
+			}
+			res.Relationships[name] = rel
+		}
+	}
+	if primary {
+		res.Links = map[string]string{"self": b.selfLink(res.ID)}
+		b.primary[key] = true
+	}
+	return res, nil
+}
+
+func (b *jsonapiBuilder) relationship(name string, v reflect.Value) (*jsonapiRelationship, error) {
+	includeIt := b.include == nil || b.include[name]
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		linkage := make([]jsonapiIdentifier, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res, err := b.resource(v.Index(i), false)
+			if err != nil {
+				return nil, err
+			}
+			if res == nil {
+				continue
+			}
+			linkage = append(linkage, jsonapiIdentifier{Type: res.Type, ID: res.ID})
+			if includeIt {
+				b.addIncluded(res)
+			}
+		}
+		return &jsonapiRelationship{Data: linkage}, nil
+	}
+
+	res, err := b.resource(v, false)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return &jsonapiRelationship{Data: nil}, nil
+	}
+	if includeIt {
+		b.addIncluded(res)
+	}
+	return &jsonapiRelationship{Data: jsonapiIdentifier{Type: res.Type, ID: res.ID}}, nil
+}
+
+// addIncluded adds res to the included resources, unless it is only a link
+// to a resource still being built, which adds itself once complete.
+func (b *jsonapiBuilder) addIncluded(res *jsonapiResource) {
+	key := jsonapiIdentifier{Type: res.Type, ID: res.ID}
+	if b.seen[key] || b.visiting[key] {
+		return
+	}
+	b.seen[key] = true
+	b.included = append(b.included, res)
+}
+
+func jsonapiFormatID(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// handleJSONAPIError renders err as a JSON:API error document.
+func handleJSONAPIError(c *gin.Context, err error, fallbackStatus int) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		status := fallbackStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		_ = c.Error(err)
+		httpErr = &HTTPError{Status: status, Message: "internal server error"}
+	}
+
+	item := jsonapiError{
+		ID:     httpErr.CorrelationID,
+		Status: strconv.Itoa(httpErr.Status),
+		Code:   httpErr.Code,
+		Title:  http.StatusText(httpErr.Status),
+		Detail: httpErr.Message,
+	}
+	if httpErr.Details != nil {
+		item.Meta = map[string]any{"details": httpErr.Details}
+	}
+	c.Header("Content-Type", JSONAPIMediaType)
+	c.AbortWithStatusJSON(httpErr.Status, gin.H{"errors": []jsonapiError{item}})
+}
@@ -0,0 +1,331 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type jsonapiPerson struct {
+	ID   int    `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type jsonapiArticle struct {
+	ID       string           `jsonapi:"primary,articles"`
+	Title    string           `jsonapi:"attr,title"`
+	Subtitle string           `jsonapi:"attr,subtitle,omitempty"`
+	Views    int              `jsonapi:"meta,views"`
+	Author   *jsonapiPerson   `jsonapi:"relation,author"`
+	Editors  []*jsonapiPerson `jsonapi:"relation,editors"`
+	Internal string
+}
+
+type articleResource struct {
+	posted map[string]any
+}
+
+func (r *articleResource) List(c *gin.Context) (any, int, error) {
+	author := &jsonapiPerson{ID: 9, Name: "alice"}
+	return JSONAPIResult{
+		Data: []jsonapiArticle{
+			{ID: "1", Title: "first", Author: author},
+			{ID: "2", Title: "second", Author: author},
+		},
+		Meta: map[string]any{"total": 2},
+	}, http.StatusOK, nil
+}
+
+func (r *articleResource) Get(id string, c *gin.Context) (any, int, error) {
+	if id != "1" {
+		return nil, 0, Abort(http.StatusNotFound, "article not found", WithCode("NOT_FOUND"))
+	}
+	return &jsonapiArticle{
+		ID:       "1",
+		Title:    "first",
+		Views:    10,
+		Author:   &jsonapiPerson{ID: 9, Name: "alice"},
+		Editors:  []*jsonapiPerson{{ID: 3, Name: "bob"}},
+		Internal: "secret",
+	}, http.StatusOK, nil
+}
+
+func (r *articleResource) Post(c *gin.Context) (any, int, error) {
+	var body map[string]any
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return nil, 0, Abort(http.StatusBadRequest, err.Error())
+	}
+	r.posted = body
+	return &jsonapiArticle{ID: "3", Title: body["title"].(string)}, http.StatusCreated, nil
+}
+
+func (r *articleResource) Patch(id string, c *gin.Context) (any, int, error) {
+	return &jsonapiArticle{ID: id, Title: "patched"}, http.StatusOK, nil
+}
+
+// jsonapiWriter refers back to the posts they wrote.
+type jsonapiWriter struct {
+	ID    int            `jsonapi:"primary,people"`
+	Name  string         `jsonapi:"attr,name"`
+	Posts []*jsonapiPost `jsonapi:"relation,posts"`
+}
+
+type jsonapiPost struct {
+	ID     string         `jsonapi:"primary,posts"`
+	Title  string         `jsonapi:"attr,title"`
+	Writer *jsonapiWriter `jsonapi:"relation,writer"`
+}
+
+type postResource struct{}
+
+func (r *postResource) Get(id string, c *gin.Context) (any, int, error) {
+	writer := &jsonapiWriter{ID: 9, Name: "alice"}
+	first := &jsonapiPost{ID: "1", Title: "first", Writer: writer}
+	second := &jsonapiPost{ID: "2", Title: "second", Writer: writer}
+	writer.Posts = []*jsonapiPost{first, second}
+	return first, http.StatusOK, nil
+}
+
+func (r *articleResource) Delete(id string, c *gin.Context) (any, int, error) {
+	return nil, http.StatusNoContent, nil
+}
+
+// --- helpers ---
+
+func setupJSONAPIRouter(resource any) *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithJSONAPI())
+	api.AddResource("/articles", resource)
+	return engine
+}
+
+func doJSONAPIRequest(engine *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", JSONAPIMediaType)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// --- tests ---
+
+func TestJSONAPI_Get_RendersResourceObject(t *testing.T) {
+	engine := setupJSONAPIRouter(&articleResource{})
+
+	w := doRequest(engine, "GET", "/api/articles/1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, JSONAPIMediaType) {
+		t.Errorf("expected JSON:API content type, got %q", ct)
+	}
+
+	var doc struct {
+		Data struct {
+			Type          string                     `json:"type"`
+			ID            string                     `json:"id"`
+			Attributes    map[string]any             `json:"attributes"`
+			Relationships map[string]json.RawMessage `json:"relationships"`
+			Links         map[string]string          `json:"links"`
+			Meta          map[string]any             `json:"meta"`
+		} `json:"data"`
+		Included []map[string]any  `json:"included"`
+		Links    map[string]string `json:"links"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if doc.Data.Type != "articles" || doc.Data.ID != "1" {
+		t.Errorf("expected articles/1, got %s/%s", doc.Data.Type, doc.Data.ID)
+	}
+	if doc.Data.Attributes["title"] != "first" {
+		t.Errorf("expected title attribute, got %v", doc.Data.Attributes)
+	}
+	if _, ok := doc.Data.Attributes["subtitle"]; ok {
+		t.Error("omitempty attribute should be skipped")
+	}
+	if _, ok := doc.Data.Attributes["Internal"]; ok {
+		t.Error("untagged field should not be serialized")
+	}
+	if doc.Data.Meta["views"] != float64(10) {
+		t.Errorf("expected views meta, got %v", doc.Data.Meta)
+	}
+	if doc.Data.Links["self"] != "/api/articles/1" {
+		t.Errorf("expected self link, got %v", doc.Data.Links)
+	}
+	if string(doc.Data.Relationships["author"]) != `{"data":{"type":"people","id":"9"}}` {
+		t.Errorf("unexpected author relationship: %s", doc.Data.Relationships["author"])
+	}
+	if len(doc.Included) != 2 {
+		t.Errorf("expected 2 included resources, got %d", len(doc.Included))
+	}
+	if doc.Links["self"] != "/api/articles/1" {
+		t.Errorf("expected top-level self link, got %v", doc.Links)
+	}
+}
+
+func TestJSONAPI_List_DeduplicatesIncludedAndAddsMeta(t *testing.T) {
+	engine := setupJSONAPIRouter(&articleResource{})
+
+	w := doRequest(engine, "GET", "/api/articles", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var doc struct {
+		Data     []map[string]any `json:"data"`
+		Included []map[string]any `json:"included"`
+		Meta     map[string]any   `json:"meta"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(doc.Data) != 2 {
+		t.Errorf("expected 2 resources, got %d", len(doc.Data))
+	}
+	if len(doc.Included) != 1 {
+		t.Errorf("expected the shared author once in included, got %d", len(doc.Included))
+	}
+	if doc.Meta["total"] != float64(2) {
+		t.Errorf("expected top-level meta, got %v", doc.Meta)
+	}
+}
+
+func TestJSONAPI_IncludeParameter_RestrictsIncluded(t *testing.T) {
+	engine := setupJSONAPIRouter(&articleResource{})
+
+	w := doRequest(engine, "GET", "/api/articles/1?include=editors", "")
+	var doc struct {
+		Included []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"included"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(doc.Included) != 1 || doc.Included[0].ID != "3" {
+		t.Errorf("expected only the editor to be included, got %+v", doc.Included)
+	}
+}
+
+func TestJSONAPI_Post_FlattensRequestDocument(t *testing.T) {
+	resource := &articleResource{}
+	engine := setupJSONAPIRouter(resource)
+
+	w := doJSONAPIRequest(engine, "POST", "/api/articles", `{
+		"data": {
+			"type": "articles",
+			"attributes": {"title": "hello"},
+			"relationships": {
+				"author": {"data": {"type": "people", "id": "9"}},
+				"editors": {"data": [{"type": "people", "id": "3"}]}
+			}
+		}
+	}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if resource.posted["title"] != "hello" {
+		t.Errorf("expected flattened title, got %v", resource.posted)
+	}
+	if resource.posted["author"] != "9" {
+		t.Errorf("expected author id, got %v", resource.posted["author"])
+	}
+	editors, ok := resource.posted["editors"].([]any)
+	if !ok || len(editors) != 1 || editors[0] != "3" {
+		t.Errorf("expected editor ids, got %v", resource.posted["editors"])
+	}
+}
+
+func TestJSONAPI_Post_MissingType_Returns400(t *testing.T) {
+	engine := setupJSONAPIRouter(&articleResource{})
+
+	w := doJSONAPIRequest(engine, "POST", "/api/articles", `{"data":{"attributes":{"title":"x"}}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestJSONAPI_Patch_IDMismatch_Returns409(t *testing.T) {
+	engine := setupJSONAPIRouter(&articleResource{})
+
+	w := doJSONAPIRequest(engine, "PATCH", "/api/articles/1", `{"data":{"type":"articles","id":"2"}}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+}
+
+func TestJSONAPI_Error_RendersErrorsArray(t *testing.T) {
+	engine := setupJSONAPIRouter(&articleResource{})
+
+	w := doRequest(engine, "GET", "/api/articles/404", "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+
+	var doc struct {
+		Errors []map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(doc.Errors) != 1 {
+		t.Fatalf("expected one error, got %v", doc.Errors)
+	}
+	if doc.Errors[0]["status"] != "404" || doc.Errors[0]["code"] != "NOT_FOUND" || doc.Errors[0]["detail"] != "article not found" {
+		t.Errorf("unexpected error object: %v", doc.Errors[0])
+	}
+}
+
+func TestJSONAPI_NoContent_EmptyBody(t *testing.T) {
+	engine := setupJSONAPIRouter(&articleResource{})
+
+	w := doRequest(engine, "DELETE", "/api/articles/1", "")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("expected empty 204, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestJSONAPI_UntaggedResult_Returns500(t *testing.T) {
+	engine := setupJSONAPIRouter(&fullCRUDResource{})
+
+	w := doRequest(engine, "GET", "/api/articles/1", "")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for a result without jsonapi tags, got %d", w.Code)
+	}
+}
+
+func TestJSONAPI_CyclicRelationships(t *testing.T) {
+	engine := setupJSONAPIRouter(&postResource{})
+
+	w := doRequest(engine, "GET", "/api/articles/1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var doc struct {
+		Included []struct {
+			Type          string                     `json:"type"`
+			ID            string                     `json:"id"`
+			Relationships map[string]json.RawMessage `json:"relationships"`
+		} `json:"included"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	// The writer and their other post are included once, complete.
+	if len(doc.Included) != 2 {
+		t.Fatalf("expected 2 included resources, got %s", w.Body.String())
+	}
+	for _, res := range doc.Included {
+		if len(res.Relationships) == 0 {
+			t.Errorf("expected %s/%s to be complete, got %s", res.Type, res.ID, w.Body.String())
+		}
+	}
+}
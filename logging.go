@@ -0,0 +1,119 @@
+package restful
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogOption configures WithLogger.
+type LogOption func(*logConfig)
+
+type logConfig struct {
+	logger *slog.Logger
+	level  func(status int) slog.Level
+	sample float64
+	redact []string
+}
+
+// WithLogLevel sets the level of the record for a response status. The
+// default logs 5xx responses at slog.LevelError, 4xx responses at
+// slog.LevelWarn and others at slog.LevelInfo.
+func WithLogLevel(level func(status int) slog.Level) LogOption {
+	return func(cfg *logConfig) {
+		cfg.level = level
+	}
+}
+
+// WithLogSampling logs only the given fraction, between 0 and 1, of
+// successful calls. Error responses are always logged.
+func WithLogSampling(rate float64) LogOption {
+	return func(cfg *logConfig) {
+		cfg.sample = rate
+	}
+}
+
+// WithLogRedaction replaces the values of the named attributes, such as
+// "id", "principal" or "error", with "[REDACTED]".
+func WithLogRedaction(keys ...string) LogOption {
+	return func(cfg *logConfig) {
+		cfg.redact = append(cfg.redact, keys...)
+	}
+}
+
+// WithLogger logs one record per resource call to logger, or to
+// slog.Default when it is nil, including calls rejected by middleware such
+// as authentication or rate limiting. Records carry the attributes
+// resource, operation, method, route, id, status and latency, plus
+// error_code and error for error responses, principal for authenticated
+// requests and request_id when the request has one.
+func WithLogger(logger *slog.Logger, opts ...LogOption) APIOption {
+	cfg := &logConfig{logger: logger, level: defaultLogLevel, sample: 1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.logger == nil {
+		cfg.logger = slog.Default()
+	}
+	return WithObserver(cfg)
+}
+
+func defaultLogLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// StartCall implements Observer.
+func (cfg *logConfig) StartCall(c *gin.Context, _ Operation) func(CallResult) {
+	return func(result CallResult) {
+		// Error responses written without an error, such as binding
+		// failures, are never sampled out either.
+		failed := result.Err != nil || result.Status >= http.StatusBadRequest
+		if !failed && cfg.sample < 1 && rand.Float64() >= cfg.sample {
+			return
+		}
+		ctx := c.Request.Context()
+		level := cfg.level(result.Status)
+		if !cfg.logger.Enabled(ctx, level) {
+			return
+		}
+
+		op := result.Operation
+		attrs := []slog.Attr{
+			slog.String("resource", op.Resource),
+			slog.String("operation", op.Name),
+			slog.String("method", op.Method),
+			slog.String("route", op.Route),
+		}
+		if op.ID != "" {
+			attrs = append(attrs, slog.String("id", op.ID))
+		}
+		attrs = append(attrs, slog.Int("status", result.Status), slog.Duration("latency", result.Duration))
+		if result.Err != nil {
+			if result.ErrorCode != "" {
+				attrs = append(attrs, slog.String("error_code", result.ErrorCode))
+			}
+			attrs = append(attrs, slog.String("error", result.Err.Error()))
+		}
+		if p, ok := PrincipalFrom(c); ok && p.Subject != "" {
+			attrs = append(attrs, slog.String("principal", p.Subject))
+		}
+		if id := RequestID(c); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		for i, a := range attrs {
+			if slices.Contains(cfg.redact, a.Key) {
+				attrs[i] = slog.String(a.Key, "[REDACTED]")
+			}
+		}
+		cfg.logger.LogAttrs(ctx, level, "resource call", attrs...)
+	}
+}
@@ -0,0 +1,142 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logRecords decodes the JSON records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	engine := gin.New()
+	api := NewAPI(engine, "/api",
+		WithLogger(newTestLogger(&buf)),
+		WithErrorMapping(errTaskNotFound, http.StatusNotFound, "TASK_NOT_FOUND"),
+	)
+	api.AddResource("/tasks", &failingResource{})
+	api.AddResource("/users", &fullCRUDResource{})
+
+	doRequest(engine, "GET", "/api/users/7", "")
+	doRequestWithHeaders(engine, "GET", "/api/tasks/missing", map[string]string{RequestIDHeader: "req-1"})
+	doRequest(engine, "GET", "/api/tasks/other", "")
+
+	records := logRecords(t, &buf)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d: %s", len(records), buf.String())
+	}
+
+	ok := records[0]
+	if ok["level"] != "INFO" || ok["msg"] != "resource call" || ok["resource"] != "users" ||
+		ok["operation"] != "Get" || ok["route"] != "/api/users/:id" || ok["id"] != "7" || ok["status"] != float64(200) {
+		t.Errorf("unexpected record %v", ok)
+	}
+	if _, has := ok["latency"]; !has {
+		t.Error("expected a latency attribute")
+	}
+	if _, has := ok["error"]; has {
+		t.Error("expected no error attribute on success")
+	}
+
+	notFound := records[1]
+	if notFound["level"] != "WARN" || notFound["status"] != float64(404) || notFound["error_code"] != "TASK_NOT_FOUND" ||
+		notFound["error"] != "repo: load task missing: task not found" || notFound["request_id"] != "req-1" {
+		t.Errorf("unexpected record %v", notFound)
+	}
+
+	if records[2]["level"] != "ERROR" || records[2]["error"] != "unexpected" {
+		t.Errorf("unexpected record %v", records[2])
+	}
+}
+
+func TestLogger_RejectedByMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithLogger(newTestLogger(&buf)))
+	api.Use(func(c *gin.Context) {
+		SetPrincipal(c, &Principal{Subject: "alice"})
+		RenderError(c, Abort(http.StatusForbidden, "forbidden", WithCode("FORBIDDEN")))
+	})
+	api.AddResource("/users", &fullCRUDResource{})
+
+	doRequest(engine, "DELETE", "/api/users/3", "")
+	records := logRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	r := records[0]
+	if r["operation"] != "Delete" || r["id"] != "3" || r["status"] != float64(403) ||
+		r["error_code"] != "FORBIDDEN" || r["principal"] != "alice" {
+		t.Errorf("unexpected record %v", r)
+	}
+}
+
+func TestLogger_SamplingKeepsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithLogger(newTestLogger(&buf), WithLogSampling(0)))
+	api.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Reject") != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "rejected"})
+		}
+	})
+	api.AddResource("/users", &fullCRUDResource{})
+	api.AddResource("/tasks", &failingResource{})
+
+	for range 10 {
+		doRequest(engine, "GET", "/api/users", "")
+	}
+	doRequest(engine, "GET", "/api/tasks/other", "")
+	// Error responses written directly are kept too.
+	doRequestWithHeaders(engine, "GET", "/api/users", map[string]string{"X-Reject": "1"})
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 || records[0]["status"] != float64(500) || records[1]["status"] != float64(403) {
+		t.Errorf("expected only the errors to be logged, got %s", buf.String())
+	}
+}
+
+func TestLogger_LevelAndRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithLogger(newTestLogger(&buf),
+		WithLogLevel(func(status int) slog.Level { return slog.LevelDebug }),
+		WithLogRedaction("id", "error"),
+	))
+	api.AddResource("/tasks", &failingResource{})
+
+	doRequest(engine, "GET", "/api/tasks/secret", "")
+	records := logRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	r := records[0]
+	if r["level"] != "DEBUG" || r["id"] != "[REDACTED]" || r["error"] != "[REDACTED]" || r["operation"] != "Get" {
+		t.Errorf("unexpected record %v", r)
+	}
+}
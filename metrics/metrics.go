@@ -0,0 +1,293 @@
+// Package metrics collects metrics about the resource calls of a
+// gin-restful API and exposes them in the Prometheus text format.
+//
+//	m := metrics.New()
+//	api := restful.NewAPI(engine, "/api", restful.WithObserver(m))
+//	engine.GET("/metrics", gin.WrapH(m))
+//
+// The collector records, labelled by resource and operation:
+//
+//	restful_requests_total             calls, by method and status code
+//	restful_errors_total               error responses, by HTTPError code
+//	restful_requests_in_flight         calls in progress
+//	restful_request_duration_seconds   latency histogram
+//
+// and, for resources using restful.WithConcurrencyLimit, labelled by
+// resource:
+//
+//	restful_concurrency_limit          calls allowed at once
+//	restful_concurrency_active         calls holding a slot
+//	restful_concurrency_queued         calls waiting for a slot
+//	restful_concurrency_shed_total     calls rejected
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+)
+
+// DefaultBuckets are the latency histogram buckets, in seconds, used unless
+// WithBuckets is given. They match the Prometheus client defaults.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithNamespace sets the prefix of metric names. The default is "restful".
+func WithNamespace(namespace string) Option {
+	return func(m *Collector) {
+		m.namespace = namespace
+	}
+}
+
+// WithBuckets sets the upper bounds, in seconds, of the latency histogram
+// buckets.
+func WithBuckets(buckets ...float64) Option {
+	return func(m *Collector) {
+		m.buckets = slices.Sorted(slices.Values(buckets))
+	}
+}
+
+// Collector is a restful.Observer recording metrics about resource calls,
+// and an http.Handler serving them in the Prometheus text exposition
+// format. It is safe for concurrent use and may observe several APIs.
+type Collector struct {
+	namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	errors    map[errorKey]uint64
+	inFlight  map[opKey]int64
+	durations map[opKey]*histogram
+	limiters  map[string]restful.ConcurrencyStats
+}
+
+type opKey struct {
+	resource, operation string
+}
+
+type requestKey struct {
+	opKey
+	method string
+	status int
+}
+
+type errorKey struct {
+	opKey
+	code string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// New returns an empty Collector.
+func New(opts ...Option) *Collector {
+	m := &Collector{
+		namespace: "restful",
+		buckets:   DefaultBuckets,
+		requests:  make(map[requestKey]uint64),
+		errors:    make(map[errorKey]uint64),
+		inFlight:  make(map[opKey]int64),
+		durations: make(map[opKey]*histogram),
+		limiters:  make(map[string]restful.ConcurrencyStats),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// StartCall implements restful.Observer.
+func (m *Collector) StartCall(_ *gin.Context, op restful.Operation) func(restful.CallResult) {
+	started := opKey{op.Resource, op.Name}
+	m.mu.Lock()
+	m.inFlight[started]++
+	m.mu.Unlock()
+
+	return func(result restful.CallResult) {
+		key := opKey{result.Operation.Resource, result.Operation.Name}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inFlight[started]--
+		m.requests[requestKey{key, result.Operation.Method, result.Status}]++
+		if result.Err != nil {
+			m.errors[errorKey{key, result.ErrorCode}]++
+		}
+		h, ok := m.durations[key]
+		if !ok {
+			h = &histogram{counts: make([]uint64, len(m.buckets))}
+			m.durations[key] = h
+		}
+		seconds := result.Duration.Seconds()
+		if i, _ := slices.BinarySearch(m.buckets, seconds); i < len(m.buckets) {
+			h.counts[i]++
+		}
+		h.count++
+		h.sum += seconds
+	}
+}
+
+// ConcurrencyChanged implements restful.ConcurrencyObserver.
+func (m *Collector) ConcurrencyChanged(stats restful.ConcurrencyStats) {
+	m.mu.Lock()
+	m.limiters[stats.Resource] = stats
+	m.mu.Unlock()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = m.WriteTo(w)
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (m *Collector) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	m.mu.Lock()
+	m.write(cw)
+	m.mu.Unlock()
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+func (m *Collector) write(w *countingWriter) {
+	name := m.namespace + "_requests_total"
+	w.header(name, "counter", "Resource calls handled, by method and status code.")
+	for _, k := range sortedKeys(m.requests, func(k requestKey) string {
+		return fmt.Sprintf("%s\x00%s\x00%s\x00%03d", k.resource, k.operation, k.method, k.status)
+	}) {
+		w.sample(name, labels(k.opKey, "method", k.method, "code", strconv.Itoa(k.status)), float64(m.requests[k]))
+	}
+
+	name = m.namespace + "_errors_total"
+	w.header(name, "counter", "Resource calls answered with an error, by error code.")
+	for _, k := range sortedKeys(m.errors, func(k errorKey) string {
+		return k.resource + "\x00" + k.operation + "\x00" + k.code
+	}) {
+		w.sample(name, labels(k.opKey, "error_code", k.code), float64(m.errors[k]))
+	}
+
+	name = m.namespace + "_requests_in_flight"
+	w.header(name, "gauge", "Resource calls in progress.")
+	for _, k := range sortedKeys(m.inFlight, opKey.String) {
+		w.sample(name, labels(k), float64(m.inFlight[k]))
+	}
+
+	name = m.namespace + "_request_duration_seconds"
+	w.header(name, "histogram", "Latency of resource calls in seconds.")
+	for _, k := range sortedKeys(m.durations, opKey.String) {
+		h := m.durations[k]
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += h.counts[i]
+			w.sample(name+"_bucket", labels(k, "le", formatFloat(bound)), float64(cumulative))
+		}
+		w.sample(name+"_bucket", labels(k, "le", "+Inf"), float64(h.count))
+		w.sample(name+"_sum", labels(k), h.sum)
+		w.sample(name+"_count", labels(k), float64(h.count))
+	}
+
+	if len(m.limiters) == 0 {
+		return
+	}
+	resources := slices.Sorted(maps.Keys(m.limiters))
+	for _, g := range []struct {
+		name, typ, help string
+		value           func(restful.ConcurrencyStats) float64
+	}{
+		{"_concurrency_limit", "gauge", "Calls allowed to run at once.", func(s restful.ConcurrencyStats) float64 { return float64(s.Limit) }},
+		{"_concurrency_active", "gauge", "Calls holding a concurrency slot.", func(s restful.ConcurrencyStats) float64 { return float64(s.Active) }},
+		{"_concurrency_queued", "gauge", "Calls waiting for a concurrency slot.", func(s restful.ConcurrencyStats) float64 { return float64(s.Queued) }},
+		{"_concurrency_shed_total", "counter", "Calls rejected by the concurrency limit.", func(s restful.ConcurrencyStats) float64 { return float64(s.Shed) }},
+	} {
+		name := m.namespace + g.name
+		w.header(name, g.typ, g.help)
+		for _, resource := range resources {
+			w.sample(name, `resource="`+escapeLabel(resource)+`"`, g.value(m.limiters[resource]))
+		}
+	}
+}
+
+func (k opKey) String() string {
+	return k.resource + "\x00" + k.operation
+}
+
+// sortedKeys returns the keys of m ordered by sortKey, so that the output is
+// stable.
+func sortedKeys[K comparable, V any](m map[K]V, sortKey func(K) string) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b K) int {
+		return strings.Compare(sortKey(a), sortKey(b))
+	})
+	return keys
+}
+
+// labels formats the resource and operation labels of k followed by the
+// given name and value pairs.
+func labels(k opKey, pairs ...string) string {
+	var b strings.Builder
+	b.WriteString(`resource="` + escapeLabel(k.resource) + `",operation="` + escapeLabel(k.operation) + `"`)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		b.WriteString("," + pairs[i] + `="` + escapeLabel(pairs[i+1]) + `"`)
+	}
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter writes the exposition format, keeping the first error and
+// the number of bytes written.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *countingWriter) header(name, typ, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (w *countingWriter) sample(name, labels string, value float64) {
+	w.printf("%s{%s} %s\n", name, labels, formatFloat(value))
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+)
+
+// --- helpers ---
+
+type userResource struct {
+	release chan struct{}
+}
+
+func (r *userResource) Get(id string, c *gin.Context) (any, int, error) {
+	if id == "missing" {
+		return nil, 0, restful.Abort(http.StatusNotFound, "not found", restful.WithCode("USER_NOT_FOUND"))
+	}
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+func (r *userResource) List(c *gin.Context) (any, int, error) {
+	<-r.release
+	return []any{}, http.StatusOK, nil
+}
+
+func setupMetricsRouter(m *Collector, resource *userResource) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	api := restful.NewAPI(engine, "/api", restful.WithObserver(m))
+	api.AddResource("/users", resource)
+	engine.GET("/metrics", gin.WrapH(m))
+	return engine
+}
+
+func get(engine *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+// --- tests ---
+
+func TestCollector(t *testing.T) {
+	m := New(WithBuckets(10, 0.5))
+	engine := setupMetricsRouter(m, &userResource{})
+
+	get(engine, "/api/users/1")
+	get(engine, "/api/users/2")
+	get(engine, "/api/users/missing")
+
+	w := get(engine, "/metrics")
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE restful_requests_total counter\n",
+		`restful_requests_total{resource="users",operation="Get",method="GET",code="200"} 2` + "\n",
+		`restful_requests_total{resource="users",operation="Get",method="GET",code="404"} 1` + "\n",
+		`restful_errors_total{resource="users",operation="Get",error_code="USER_NOT_FOUND"} 1` + "\n",
+		`restful_requests_in_flight{resource="users",operation="Get"} 0` + "\n",
+		"# TYPE restful_request_duration_seconds histogram\n",
+		`restful_request_duration_seconds_bucket{resource="users",operation="Get",le="0.5"} 3` + "\n",
+		`restful_request_duration_seconds_bucket{resource="users",operation="Get",le="10"} 3` + "\n",
+		`restful_request_duration_seconds_bucket{resource="users",operation="Get",le="+Inf"} 3` + "\n",
+		`restful_request_duration_seconds_count{resource="users",operation="Get"} 3` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestCollector_InFlight(t *testing.T) {
+	m := New(WithNamespace("app"))
+	resource := &userResource{release: make(chan struct{})}
+	engine := setupMetricsRouter(m, resource)
+
+	done := make(chan struct{})
+	go func() {
+		get(engine, "/api/users")
+		close(done)
+	}()
+
+	inFlight := `app_requests_in_flight{resource="users",operation="List"} 1`
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(get(engine, "/metrics").Body.String(), inFlight) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the call in progress to be counted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(resource.release)
+	<-done
+
+	if body := get(engine, "/metrics").Body.String(); !strings.Contains(body, `app_requests_in_flight{resource="users",operation="List"} 0`) {
+		t.Errorf("expected no call in progress, got:\n%s", body)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("unexpected escaping %q", got)
+	}
+}
+
+func TestCollector_Concurrency(t *testing.T) {
+	m := New()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	api := restful.NewAPI(engine, "/api", restful.WithObserver(m))
+	api.AddResource("/users", &userResource{}, restful.WithConcurrencyLimit(4))
+	engine.GET("/metrics", gin.WrapH(m))
+
+	get(engine, "/api/users/1")
+	body := get(engine, "/metrics").Body.String()
+	for _, want := range []string{
+		"# TYPE restful_concurrency_limit gauge\n",
+		`restful_concurrency_limit{resource="users"} 4` + "\n",
+		`restful_concurrency_active{resource="users"} 0` + "\n",
+		`restful_concurrency_queued{resource="users"} 0` + "\n",
+		"# TYPE restful_concurrency_shed_total counter\n",
+		`restful_concurrency_shed_total{resource="users"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
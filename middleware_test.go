@@ -0,0 +1,95 @@
+package restful
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func recordMiddleware(trace *[]string, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		*trace = append(*trace, name)
+		c.Next()
+	}
+}
+
+func requireHeader(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(name) == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func TestWithMiddleware_Order(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+
+	var trace []string
+	api.Use(recordMiddleware(&trace, "api"))
+	api.AddResource("/items", &fullCRUDResource{},
+		WithMiddleware(recordMiddleware(&trace, "resource")),
+		WithMethodMiddleware(http.MethodDelete, recordMiddleware(&trace, "delete")),
+	)
+
+	doRequest(engine, "DELETE", "/api/items/1", "")
+	if len(trace) != 3 || trace[0] != "api" || trace[1] != "resource" || trace[2] != "delete" {
+		t.Errorf("expected api, resource, delete middleware in order, got %v", trace)
+	}
+
+	trace = nil
+	doRequest(engine, "GET", "/api/items/1", "")
+	if len(trace) != 2 {
+		t.Errorf("method middleware should only run for DELETE, got %v", trace)
+	}
+}
+
+func TestWithMethodMiddleware_GuardsOneVerb(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/items", &fullCRUDResource{},
+		WithMethodMiddleware(http.MethodDelete, requireHeader("X-Admin")),
+	)
+
+	w := doRequest(engine, "DELETE", "/api/items/1", "")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for DELETE without admin header, got %d", w.Code)
+	}
+	w = doRequest(engine, "PUT", "/api/items/1", `{}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for PUT, got %d", w.Code)
+	}
+}
+
+func TestAPIUse_DoesNotAffectOtherRoutes(t *testing.T) {
+	engine := gin.New()
+	engine.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	api := NewAPI(engine, "/api")
+	api.Use(requireHeader("X-User-ID"))
+	api.AddResource("/items", &readOnlyResource{})
+
+	w := doRequest(engine, "GET", "/api/items", "")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected API middleware on resource route, got %d", w.Code)
+	}
+	w = doRequest(engine, "GET", "/health", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected other routes to be unaffected, got %d", w.Code)
+	}
+}
+
+func TestWithMiddleware_Singleton(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddSingleton("/settings", &settingsResource{}, WithMiddleware(requireHeader("X-User-ID")))
+
+	w := doRequest(engine, "GET", "/api/settings", "")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package restful
+
+import (
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CallResult describes a finished resource call.
+type CallResult struct {
+	// Operation is the operation that handled the call. It can be more
+	// specific than the one the call started with: a POST to a collection
+	// implementing both Poster and BulkPoster starts as "Post" and ends as
+	// "BulkPost" when its body is an array.
+	Operation Operation
+	// Status is the status code of the response.
+	Status int
+	// Duration is the time spent in the API, middleware included.
+	Duration time.Duration
+	// Err is the error the response was rendered from, before error
+	// mapping, or nil for successful calls.
+	Err error
+	// ErrorCode is the Code of the HTTPError sent, if any.
+	ErrorCode string
+}
+
+// Observer is notified of every call to a resource of an API, including
+// calls rejected by middleware such as authentication or rate limiting.
+// StartCall runs before any middleware and returns a function, which may be
+// nil, called with the result once the response has been written. It can
+// replace c.Request, e.g. to attach a context.
+type Observer interface {
+	StartCall(c *gin.Context, op Operation) func(CallResult)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(c *gin.Context, op Operation) func(CallResult)
+
+// StartCall implements Observer.
+func (f ObserverFunc) StartCall(c *gin.Context, op Operation) func(CallResult) {
+	return f(c, op)
+}
+
+// WithObserver adds an observer of resource calls, such as a metrics
+// collector. Observers start in registration order and finish in reverse
+// order.
+func WithObserver(observer Observer) APIOption {
+	return func(api *API) {
+		api.observers = append(api.observers, observer)
+	}
+}
+
+// observe returns the middleware notifying the API's observers of calls to
+// a route. operation resolves the operation calls start with.
+func (api *API) observe(operation func(c *gin.Context) Operation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := operation(c)
+		finishers := make([]func(CallResult), 0, len(api.observers))
+		for _, observer := range api.observers {
+			if finish := observer.StartCall(c, op); finish != nil {
+				finishers = append(finishers, finish)
+			}
+		}
+		start := time.Now()
+
+		finish := func(result CallResult) {
+			result.Operation, result.Duration = op, time.Since(start)
+			if final, ok := GetOperation(c); ok {
+				result.Operation = final
+			}
+			for i := len(finishers) - 1; i >= 0; i-- {
+				finishers[i](result)
+			}
+		}
+		// Calls ending in a panic left to gin's recovery middleware are
+		// reported as 500s.
+		defer func() {
+			if v := recover(); v != nil {
+				finish(CallResult{Status: http.StatusInternalServerError, Err: &PanicError{Value: v, Stack: debug.Stack()}})
+				panic(v)
+			}
+		}()
+
+		c.Next()
+
+		result := CallResult{Status: c.Writer.Status()}
+		if e, ok := responseErrorFrom(c); ok {
+			result.Err, result.ErrorCode = e.err, e.code()
+		}
+		finish(result)
+	}
+}
@@ -0,0 +1,60 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestObserver(t *testing.T) {
+	var calls []string
+	observer := func(name string) Observer {
+		return ObserverFunc(func(c *gin.Context, op Operation) func(CallResult) {
+			calls = append(calls, fmt.Sprintf("%s start %s", name, op.Name))
+			return func(result CallResult) {
+				calls = append(calls, fmt.Sprintf("%s end %s %d %s", name, result.Operation.Name, result.Status, result.ErrorCode))
+			}
+		})
+	}
+	engine := gin.New()
+	api := NewAPI(engine, "/api",
+		WithObserver(observer("a")),
+		WithObserver(observer("b")),
+		WithErrorMapping(errTaskNotFound, http.StatusNotFound, "TASK_NOT_FOUND"),
+	)
+	api.AddResource("/tasks", &failingResource{})
+	api.AddResource("/items", &bulkResource{})
+
+	doRequest(engine, "GET", "/api/tasks/missing", "")
+	doRequest(engine, "POST", "/api/items", `[{"name":"a"}]`)
+
+	want := []string{
+		"a start Get", "b start Get", "b end Get 404 TASK_NOT_FOUND", "a end Get 404 TASK_NOT_FOUND",
+		// The shared POST route starts as Post and ends as BulkPost.
+		"a start Post", "b start Post", "b end BulkPost 207 ", "a end BulkPost 207 ",
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestObserver_Panic(t *testing.T) {
+	var got CallResult
+	engine := gin.New()
+	engine.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	api := NewAPI(engine, "/api", WithObserver(ObserverFunc(func(c *gin.Context, op Operation) func(CallResult) {
+		return func(result CallResult) { got = result }
+	})))
+	api.AddResource("/things", &panickingResource{})
+
+	doRequest(engine, "GET", "/api/things", "")
+	var panicErr *PanicError
+	if got.Status != http.StatusInternalServerError || got.Operation.Name != "List" || !errors.As(got.Err, &panicErr) {
+		t.Errorf("expected the panic to be reported as a 500, got %+v", got)
+	}
+}
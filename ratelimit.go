@@ -0,0 +1,274 @@
+package restful
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit allows Limit requests per Window. The in-memory store enforces
+// it as a token bucket holding up to Limit tokens and refilled at
+// Limit/Window, so short bursts of up to Limit requests are allowed.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (l RateLimit) validate() {
+	if l.Limit <= 0 || l.Window <= 0 {
+		panic(fmt.Sprintf("gin-restful: invalid rate limit of %d requests per %s", l.Limit, l.Window))
+	}
+}
+
+// RateLimitResult is the outcome of a rate limit check.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, set when
+	// the request is not.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore tracks request quotas. Allow records a request for key and
+// reports whether it is within limit. Implementations must be safe for
+// concurrent use; a store shared by several instances (e.g. Redis) may
+// implement any algorithm, such as a sliding window.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc returns the client a request is counted against.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitByIP counts requests per client IP as determined by
+// gin.Context.ClientIP. It is the default.
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByPrincipal counts requests per principal set with SetPrincipal,
+// and anonymous requests per client IP.
+func RateLimitByPrincipal(c *gin.Context) string {
+	if p, ok := PrincipalFrom(c); ok {
+		return "principal:" + p.Subject
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByHeader counts requests per value of the given header, e.g. an
+// API key header, and requests without it per client IP. Header values are
+// hashed before being passed to the store.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		value := c.GetHeader(name)
+		if value == "" {
+			return RateLimitByIP(c)
+		}
+		sum := sha256.Sum256([]byte(value))
+		return "header:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// RateLimitOption configures WithRateLimit and WithGlobalRateLimit.
+type RateLimitOption func(*rateLimitConfig)
+
+type rateLimitConfig struct {
+	limit      RateLimit
+	operations map[string]RateLimit
+	key        RateLimitKeyFunc
+	store      RateLimitStore
+}
+
+// WithRateLimitKey sets how clients are identified. The default is
+// RateLimitByIP.
+func WithRateLimitKey(key RateLimitKeyFunc) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.key = key
+	}
+}
+
+// WithRateLimitStore sets the store tracking quotas. The default is an
+// in-memory MemoryRateLimitStore.
+func WithRateLimitStore(store RateLimitStore) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.store = store
+	}
+}
+
+// WithOperationRateLimit gives the named operation ("List", "Post", an
+// action name, ...) its own limit and quota instead of the shared one.
+func WithOperationRateLimit(operation string, limit RateLimit) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		if cfg.operations == nil {
+			cfg.operations = make(map[string]RateLimit)
+		}
+		cfg.operations[operation] = limit
+	}
+}
+
+func newRateLimitConfig(limit RateLimit, opts []RateLimitOption) *rateLimitConfig {
+	cfg := &rateLimitConfig{limit: limit, key: RateLimitByIP}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	limit.validate()
+	for _, l := range cfg.operations {
+		l.validate()
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryRateLimitStore()
+	}
+	return cfg
+}
+
+// WithRateLimit limits requests to the resource per client. All operations
+// share one quota of limit requests per client, except those given their
+// own with WithOperationRateLimit. Requests over the limit get 429 Too Many
+// Requests with a Retry-After header; every response carries
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers. If the
+// store fails, requests are allowed and the error is recorded with c.Error.
+func WithRateLimit(limit RateLimit, opts ...RateLimitOption) ResourceOption {
+	cfg := newRateLimitConfig(limit, opts)
+	return func(rc *resourceConfig) {
+		rc.rateLimit = cfg
+	}
+}
+
+// WithGlobalRateLimit limits requests to every resource of the API with one
+// quota per client, on top of any per-resource limits. It takes the same
+// options as WithRateLimit and runs after the middleware added with Use, so
+// RateLimitByPrincipal sees the authenticated principal.
+func WithGlobalRateLimit(limit RateLimit, opts ...RateLimitOption) APIOption {
+	cfg := newRateLimitConfig(limit, opts)
+	return func(api *API) {
+		api.rateLimit = cfg
+	}
+}
+
+// handler returns the middleware enforcing cfg on a route serving the named
+// operations, with the quota of the operation serving each request. scope
+// separates the quotas of different resources.
+func (cfg *rateLimitConfig) handler(scope string, names []string) gin.HandlerFunc {
+	type quota struct {
+		limit  RateLimit
+		bucket string
+	}
+	quotas := make(map[string]quota, len(names))
+	for _, name := range names {
+		if l, ok := cfg.operations[name]; ok {
+			quotas[name] = quota{l, scope + ":" + name}
+		} else {
+			quotas[name] = quota{cfg.limit, scope}
+		}
+	}
+
+	return func(c *gin.Context) {
+		q := quotas[routeOperation(c, names)]
+		result, err := cfg.store.Allow(c.Request.Context(), q.bucket+"|"+cfg.key(c), q.limit)
+		if err != nil {
+			_ = c.Error(err)
+			c.Next()
+			return
+		}
+
+		reset := strconv.Itoa(ceilSeconds(result.Reset))
+		if !result.Allowed {
+			RenderError(c, Abort(http.StatusTooManyRequests, "rate limit exceeded",
+				WithCode("RATE_LIMITED"),
+				WithHeader("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter))),
+				WithHeader("RateLimit-Limit", strconv.Itoa(result.Limit)),
+				WithHeader("RateLimit-Remaining", "0"),
+				WithHeader("RateLimit-Reset", reset),
+			))
+			return
+		}
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", reset)
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// MemoryRateLimitStore is an in-memory token bucket RateLimitStore. It only
+// limits requests served by the same process.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket will be full again
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// Allow implements RateLimitStore.
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		return RateLimitResult{}, fmt.Errorf("gin-restful: invalid rate limit of %d requests per %s", limit.Limit, limit.Window)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	capacity := float64(limit.Limit)
+	perToken := float64(limit.Window) / capacity
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+float64(now.Sub(b.last))/perToken)
+	b.last = now
+
+	result := RateLimitResult{Limit: limit.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+		result.Remaining = int(b.tokens)
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * perToken)
+	}
+	result.Reset = time.Duration((capacity - b.tokens) * perToken)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute,
+// so that clients seen once do not accumulate.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
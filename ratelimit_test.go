@@ -0,0 +1,141 @@
+package restful
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- helpers ---
+
+func setupRateLimitRouter(apiOpts []APIOption, opts ...ResourceOption) *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", apiOpts...)
+	api.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			SetPrincipal(c, &Principal{Subject: user})
+		}
+	})
+	api.AddResource("/items", &fullCRUDResource{}, opts...)
+	api.AddResource("/orders", &orderCounterResource{})
+	return engine
+}
+
+// --- tests ---
+
+func TestRateLimit_RejectsOverLimit(t *testing.T) {
+	engine := setupRateLimitRouter(nil, WithRateLimit(RateLimit{Limit: 2, Window: time.Minute}))
+
+	for i, want := range []string{"1", "0"} {
+		w := doRequest(engine, "GET", "/api/items", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != want {
+			t.Errorf("request %d: unexpected headers %v", i, w.Header())
+		}
+	}
+
+	w := doRequest(engine, "GET", "/api/items/1", "")
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "RATE_LIMITED") {
+		t.Fatalf("expected 429, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("unexpected headers on 429: %v", w.Header())
+	}
+}
+
+func TestRateLimit_PerOperation(t *testing.T) {
+	engine := setupRateLimitRouter(nil, WithRateLimit(RateLimit{Limit: 5, Window: time.Minute},
+		WithOperationRateLimit("Post", RateLimit{Limit: 1, Window: time.Minute}),
+	))
+
+	doRequest(engine, "POST", "/api/items", `{}`)
+	if w := doRequest(engine, "POST", "/api/items", `{}`); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected second Post to be limited, got %d", w.Code)
+	}
+	if w := doRequest(engine, "GET", "/api/items", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "4" {
+		t.Errorf("expected List to use the shared quota, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestRateLimit_ByPrincipal(t *testing.T) {
+	engine := setupRateLimitRouter(nil, WithRateLimit(RateLimit{Limit: 1, Window: time.Minute}, WithRateLimitKey(RateLimitByPrincipal)))
+
+	alice := map[string]string{"X-User": "alice"}
+	doRequestWithHeaders(engine, "GET", "/api/items", alice)
+	if w := doRequestWithHeaders(engine, "GET", "/api/items", alice); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected alice to be limited, got %d", w.Code)
+	}
+	if w := doRequestWithHeaders(engine, "GET", "/api/items", map[string]string{"X-User": "bob"}); w.Code != http.StatusOK {
+		t.Errorf("expected bob to have a separate quota, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_Global(t *testing.T) {
+	engine := setupRateLimitRouter([]APIOption{WithGlobalRateLimit(RateLimit{Limit: 2, Window: time.Minute})})
+
+	doRequest(engine, "GET", "/api/items", "")
+	doRequest(engine, "GET", "/api/orders/1", "")
+	if w := doRequest(engine, "GET", "/api/items/1", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the quota to be shared across resources, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_InvalidLimitPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a zero window")
+		}
+	}()
+	WithRateLimit(RateLimit{Limit: 1})
+}
+
+func TestMemoryRateLimitStore_Refills(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Unix(0, 0)
+	store.now = func() time.Time { return now }
+	limit := RateLimit{Limit: 2, Window: 10 * time.Second}
+	ctx := t.Context()
+
+	store.Allow(ctx, "k", limit)
+	store.Allow(ctx, "k", limit)
+	if r, _ := store.Allow(ctx, "k", limit); r.Allowed || r.RetryAfter != 5*time.Second {
+		t.Fatalf("expected denial with 5s retry, got %+v", r)
+	}
+
+	now = now.Add(5 * time.Second)
+	if r, _ := store.Allow(ctx, "k", limit); !r.Allowed || r.Remaining != 0 {
+		t.Errorf("expected one refilled token, got %+v", r)
+	}
+
+	now = now.Add(time.Hour)
+	store.Allow(ctx, "other", limit)
+	if _, ok := store.buckets["k"]; ok {
+		t.Error("expected the idle bucket to be swept")
+	}
+}
+
+func TestRateLimit_PerOperationOnSharedRoute(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/items", &bulkResource{}, WithRateLimit(RateLimit{Limit: 100, Window: time.Minute},
+		WithOperationRateLimit("BulkPost", RateLimit{Limit: 1, Window: time.Hour}),
+	))
+
+	if w := doRequest(engine, "POST", "/api/items", `[{"name":"a"}]`); w.Code >= http.StatusBadRequest || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("expected the first BulkPost to pass with its own quota, got %d %v", w.Code, w.Header())
+	}
+	if w := doRequest(engine, "POST", "/api/items", `[{"name":"b"}]`); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the second BulkPost to be limited, got %d", w.Code)
+	}
+	for i := range 3 {
+		w := doRequest(engine, "POST", "/api/items", `{"name":"c"}`)
+		if w.Code != http.StatusCreated || w.Header().Get("RateLimit-Limit") != "100" {
+			t.Errorf("Post %d: expected the shared quota, got %d %v", i, w.Code, w.Header())
+		}
+	}
+}
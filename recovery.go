@@ -0,0 +1,58 @@
+package restful
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PanicError is the error a recovered panic is turned into by WithRecovery.
+// Error handlers and middleware inspecting c.Errors can use it to log the
+// panic with its stack trace.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns Value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithRecovery recovers panics in resource handlers, hooks and
+// representation, and responds with a *PanicError through the API's error
+// handler, so the response has the same format as other errors: a 500 with
+// the default handler. Panics in middleware are left to gin's recovery
+// middleware, as is http.ErrAbortHandler.
+func WithRecovery() APIOption {
+	return func(api *API) {
+		api.recovery = true
+	}
+}
+
+// recoverPanics turns panics in fn into *PanicError results.
+func recoverPanics(fn func(c *gin.Context) (any, int, error)) func(c *gin.Context) (any, int, error) {
+	return func(c *gin.Context) (result any, status int, err error) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			result, status, err = nil, http.StatusInternalServerError, &PanicError{Value: v, Stack: debug.Stack()}
+		}()
+		return fn(c)
+	}
+}
@@ -0,0 +1,97 @@
+package restful
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type panickingResource struct{}
+
+func (r *panickingResource) List(c *gin.Context) (any, int, error) {
+	panic("boom")
+}
+
+func (r *panickingResource) Get(id string, c *gin.Context) (any, int, error) {
+	panic(errors.New("nil pointer somewhere"))
+}
+
+func (r *panickingResource) Delete(id string, c *gin.Context) (any, int, error) {
+	panic(http.ErrAbortHandler)
+}
+
+// --- tests ---
+
+func TestRecovery_DefaultErrorHandler(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRecovery())
+	api.AddResource("/things", &panickingResource{})
+
+	w := doRequest(engine, "GET", "/api/things", "")
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"message":"internal server error"}` {
+		t.Errorf("expected a regular 500 response, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRecovery_CustomErrorHandlerReceivesPanicError(t *testing.T) {
+	var got *PanicError
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRecovery(), WithErrorHandler(func(c *gin.Context, err error, status int) {
+		errors.As(err, &got)
+		c.JSON(status, gin.H{"error": err.Error()})
+	}))
+	api.AddResource("/things", &panickingResource{})
+
+	w := doRequest(engine, "GET", "/api/things/1", "")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if got == nil || !strings.Contains(string(got.Stack), "panickingResource") {
+		t.Fatalf("expected a PanicError with a stack trace, got %+v", got)
+	}
+	if got.Unwrap() == nil || got.Unwrap().Error() != "nil pointer somewhere" {
+		t.Errorf("expected the panic value to be unwrapped, got %v", got.Unwrap())
+	}
+}
+
+func TestRecovery_JSONAPIErrorFormat(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRecovery(), WithJSONAPI())
+	api.AddResource("/things", &panickingResource{})
+
+	w := doRequest(engine, "GET", "/api/things", "")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"errors"`) {
+		t.Errorf("expected a JSON:API error document, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRecovery_Disabled(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/things", &panickingResource{})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected the panic to propagate without WithRecovery")
+		}
+	}()
+	doRequest(engine, "GET", "/api/things", "")
+}
+
+func TestRecovery_ErrAbortHandlerPropagates(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRecovery())
+	api.AddResource("/things", &panickingResource{})
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to propagate, got %v", v)
+		}
+	}()
+	doRequest(engine, "DELETE", "/api/things/1", "")
+}
@@ -0,0 +1,126 @@
+package restful
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is the header carrying the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDKey          = "gin-restful.request-id"
+	maxRequestIDLength    = 128
+	requestIDExtraSymbols = "-_.:+/=@"
+)
+
+type requestIDContextKey struct{}
+
+// perRequestHeaders are response headers describing the request being
+// served rather than its result, left out of responses stored for replay to
+// later requests.
+var perRequestHeaders = []string{RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"}
+
+// withoutPerRequestHeaders returns a copy of header without perRequestHeaders.
+func withoutPerRequestHeaders(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range perRequestHeaders {
+		header.Del(name)
+	}
+	return header
+}
+
+// WithRequestID gives every request handled by the API an ID: the
+// X-Request-ID header of the request when it holds a well-formed one, and a
+// new ID from generate otherwise, or a UUIDv7 when generate is nil. The ID
+// is echoed in the X-Request-ID response header, and can be read with
+// RequestID and RequestIDFromContext. Error responses carry it as their
+// correlation ID unless WithCorrelationID chooses another one.
+func WithRequestID(generate func() string) APIOption {
+	return func(api *API) {
+		if generate == nil {
+			generate = newUUIDv7
+		}
+		api.requestID = generate
+	}
+}
+
+// RequestID returns the ID of the request: the one assigned by
+// WithRequestID, or else the X-Request-ID header of the request if it is a
+// valid ID, or "".
+func RequestID(c *gin.Context) string {
+	if id := c.GetString(requestIDKey); id != "" {
+		return id
+	}
+	if id := c.GetHeader(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	return ""
+}
+
+// RequestIDFromContext returns the ID assigned by WithRequestID to the
+// request ctx belongs to, for code that only has the request's context.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// assignRequestID is the middleware assigning request IDs. Generated IDs
+// are also set on the request header so that batch sub-requests share them.
+func (api *API) assignRequestID(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if !validRequestID(id) {
+		id = api.requestID()
+		c.Request.Header.Set(RequestIDHeader, id)
+	}
+	c.Set(requestIDKey, id)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDContextKey{}, id))
+	c.Header(RequestIDHeader, id)
+	c.Next()
+}
+
+// validRequestID reports whether id is safe to reuse in logs and headers:
+// short and made of letters, digits and a few symbols.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune(requestIDExtraSymbols, r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newUUIDv7 returns a random UUID version 7 (RFC 9562), which starts with
+// the current Unix time in milliseconds so that IDs sort by creation time.
+func newUUIDv7() string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+	binary.BigEndian.PutUint64(u[:8], uint64(time.Now().UnixMilli())<<16|uint64(binary.BigEndian.Uint16(u[6:8])))
+	u[6] = 0x70 | u[6]&0x0f // version 7
+	u[8] = 0x80 | u[8]&0x3f // RFC 9562 variant
+
+	var s [36]byte
+	hex.Encode(s[0:8], u[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], u[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], u[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], u[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], u[10:])
+	return string(s[:])
+}
@@ -0,0 +1,112 @@
+package restful
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type requestIDResource struct{}
+
+func (r *requestIDResource) Get(id string, c *gin.Context) (any, int, error) {
+	if id == "missing" {
+		return nil, 0, Abort(http.StatusNotFound, "not found")
+	}
+	return gin.H{"gin": RequestID(c), "ctx": RequestIDFromContext(c.Request.Context())}, http.StatusOK, nil
+}
+
+// --- tests ---
+
+var uuidV7 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestID_Generated(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRequestID(nil))
+	api.AddResource("/things", &requestIDResource{})
+
+	w := doRequest(engine, "GET", "/api/things/1", "")
+	id := w.Header().Get(RequestIDHeader)
+	if !uuidV7.MatchString(id) {
+		t.Fatalf("expected a UUIDv7 request ID, got %q", id)
+	}
+	if w.Body.String() != `{"ctx":"`+id+`","gin":"`+id+`"}` {
+		t.Errorf("expected the ID on the gin and request contexts, got %s", w.Body.String())
+	}
+	if w2 := doRequest(engine, "GET", "/api/things/1", ""); w2.Header().Get(RequestIDHeader) == id {
+		t.Error("expected a new ID per request")
+	}
+}
+
+func TestRequestID_Incoming(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRequestID(func() string { return "generated" }))
+	api.AddResource("/things", &requestIDResource{})
+
+	w := doRequestWithHeaders(engine, "GET", "/api/things/1", map[string]string{RequestIDHeader: "edge-123"})
+	if got := w.Header().Get(RequestIDHeader); got != "edge-123" {
+		t.Errorf("expected the incoming ID to be kept, got %q", got)
+	}
+
+	for _, bad := range []string{"has space", "quote\"", strings.Repeat("a", 129)} {
+		w := doRequestWithHeaders(engine, "GET", "/api/things/1", map[string]string{RequestIDHeader: bad})
+		if got := w.Header().Get(RequestIDHeader); got != "generated" {
+			t.Errorf("expected %q to be replaced, got %q", bad, got)
+		}
+	}
+}
+
+func TestRequestID_WithoutAssignment(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/things", &requestIDResource{})
+
+	w := doRequestWithHeaders(engine, "GET", "/api/things/1", map[string]string{RequestIDHeader: "edge-123"})
+	if w.Body.String() != `{"ctx":"","gin":"edge-123"}` {
+		t.Errorf("expected the incoming ID, got %s", w.Body.String())
+	}
+	w = doRequestWithHeaders(engine, "GET", "/api/things/1", map[string]string{RequestIDHeader: "<script>"})
+	if w.Body.String() != `{"ctx":"","gin":""}` {
+		t.Errorf("expected a malformed ID to be ignored, got %s", w.Body.String())
+	}
+}
+
+func TestRequestID_InErrorBodies(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRequestID(func() string { return "req-1" }))
+	api.AddResource("/things", &requestIDResource{})
+
+	w := doRequest(engine, "GET", "/api/things/missing", "")
+	if w.Body.String() != `{"message":"not found","correlation_id":"req-1"}` {
+		t.Errorf("expected the request ID in the error body, got %s", w.Body.String())
+	}
+}
+
+func TestRequestID_Batch(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithRequestID(func() string { return "batch-1" }))
+	api.AddResource("/things", &requestIDResource{})
+	api.EnableBatch("/batch")
+
+	w := doRequest(engine, "POST", "/api/batch", `[{"method":"GET","path":"/api/things/1"}]`)
+	if w.Header().Get(RequestIDHeader) != "batch-1" || !strings.Contains(w.Body.String(), `"gin":"batch-1"`) {
+		t.Errorf("expected sub-requests to share the batch request ID, got %s", w.Body.String())
+	}
+}
+
+func TestNewUUIDv7_SortsByTime(t *testing.T) {
+	a := newUUIDv7()
+	time.Sleep(2 * time.Millisecond)
+	b := newUUIDv7()
+	if !uuidV7.MatchString(a) || !uuidV7.MatchString(b) {
+		t.Fatalf("malformed UUIDs %q %q", a, b)
+	}
+	if a >= b {
+		t.Errorf("expected %q to sort before %q", a, b)
+	}
+}
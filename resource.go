@@ -4,13 +4,26 @@
 // Only implemented interfaces are registered as routes.
 package restful
 
-import "github.com/gin-gonic/gin"
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
 
 // Lister handles GET requests on a collection path (e.g. GET /items).
 type Lister interface {
 	List(c *gin.Context) (any, int, error)
 }
 
+// StreamLister handles GET requests on a collection path by streaming the
+// items, for collections too large to build in memory. The items are
+// written as they are produced, as a JSON array or as newline-delimited
+// JSON when the request accepts NDJSONMediaType; see Stream. A resource
+// implementing both StreamLister and Lister is listed with StreamList.
+type StreamLister interface {
+	StreamList(c *gin.Context) (Stream, error)
+}
+
 // Getter handles GET requests for a single resource (e.g. GET /items/:id).
 type Getter interface {
 	Get(id string, c *gin.Context) (any, int, error)
@@ -35,3 +48,50 @@ type Patcher interface {
 type Deleter interface {
 	Delete(id string, c *gin.Context) (any, int, error)
 }
+
+// BulkPoster handles POST requests with a JSON array body on a collection path
+// (e.g. POST /items with [{...}, {...}]). It returns one BulkResult per item.
+// When the resource also implements Poster, requests whose body is not a JSON
+// array are routed to Post.
+type BulkPoster interface {
+	BulkPost(items []json.RawMessage, c *gin.Context) ([]BulkResult, error)
+}
+
+// BulkPatcher handles PATCH requests with a JSON array body on a collection
+// path (e.g. PATCH /items). Each item carries its own id. It returns one
+// BulkResult per item.
+type BulkPatcher interface {
+	BulkPatch(items []json.RawMessage, c *gin.Context) ([]BulkResult, error)
+}
+
+// BulkDeleter handles DELETE requests with a JSON array of ids on a collection
+// path (e.g. DELETE /items with ["1", "2"]). It returns one BulkResult per id.
+type BulkDeleter interface {
+	BulkDelete(ids []string, c *gin.Context) ([]BulkResult, error)
+}
+
+// SingletonGetter handles GET requests for a singleton resource (e.g. GET /me).
+type SingletonGetter interface {
+	Get(c *gin.Context) (any, int, error)
+}
+
+// SingletonPutter handles PUT requests to replace a singleton resource (e.g. PUT /me).
+type SingletonPutter interface {
+	Put(c *gin.Context) (any, int, error)
+}
+
+// SingletonPatcher handles PATCH requests to partially update a singleton resource (e.g. PATCH /me).
+type SingletonPatcher interface {
+	Patch(c *gin.Context) (any, int, error)
+}
+
+// SingletonDeleter handles DELETE requests to remove a singleton resource (e.g. DELETE /me).
+type SingletonDeleter interface {
+	Delete(c *gin.Context) (any, int, error)
+}
+
+// Actioner declares custom actions beyond the CRUD verbs (e.g. POST
+// /orders/:id/cancel). See ItemAction and CollectionAction.
+type Actioner interface {
+	Actions() []Action
+}
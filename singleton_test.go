@@ -0,0 +1,146 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type settingsResource struct {
+	theme string
+}
+
+func (r *settingsResource) Get(c *gin.Context) (any, int, error) {
+	return gin.H{"theme": r.theme}, http.StatusOK, nil
+}
+
+func (r *settingsResource) Put(c *gin.Context) (any, int, error) {
+	body, err := Bind[struct {
+		Theme string `json:"theme" binding:"required"`
+	}](c)
+	if err != nil {
+		return nil, 0, Abort(http.StatusBadRequest, err.Error())
+	}
+	r.theme = body.Theme
+	return gin.H{"theme": r.theme}, http.StatusOK, nil
+}
+
+func (r *settingsResource) Delete(c *gin.Context) (any, int, error) {
+	r.theme = ""
+	return nil, http.StatusNoContent, nil
+}
+
+type profileResource struct{}
+
+func (r *profileResource) Get(c *gin.Context) (any, int, error) {
+	return gin.H{"user_id": c.Param("id")}, http.StatusOK, nil
+}
+
+func (r *profileResource) Patch(c *gin.Context) (any, int, error) {
+	return gin.H{"user_id": c.Param("id"), "patched": true}, http.StatusOK, nil
+}
+
+// --- tests ---
+
+func TestAddSingleton_RoutesWithoutID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddSingleton("/settings", &settingsResource{theme: "dark"})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"GET", "/api/settings", "", 200},
+		{"PUT", "/api/settings", `{"theme":"light"}`, 200},
+		{"PUT", "/api/settings", `{}`, 400},
+		{"DELETE", "/api/settings", "", 204},
+		{"PATCH", "/api/settings", `{}`, 404},
+		{"GET", "/api/settings/1", "", 404},
+	}
+
+	for _, tt := range tests {
+		w := doRequest(engine, tt.method, tt.path, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+	}
+}
+
+func TestAddSingleton_NestedUnderParent(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/users", &readOnlyResource{})
+	api.AddSingleton("/users/:id/profile", &profileResource{})
+
+	w := doRequest(engine, "GET", "/api/users/7/profile", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if resp["user_id"] != "7" {
+		t.Errorf("expected parent id 7, got %q", resp["user_id"])
+	}
+
+	// The parent's own routes keep working.
+	w = doRequest(engine, "GET", "/api/users/1", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /api/users/1: expected 200, got %d", w.Code)
+	}
+}
+
+func TestAddSingleton_NoInterface_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for singleton implementing no interfaces")
+		}
+	}()
+
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddSingleton("/me", &readOnlyResource{}) // Getter, not SingletonGetter
+}
+
+func TestAddSingleton_ItemAction_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for item action on a singleton")
+		}
+	}()
+
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddSingleton("/orders", &orderResource{})
+}
+
+func TestAddSingleton_HALLinks(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithHAL())
+	api.AddResource("/users", &halUserResource{})
+	api.AddSingleton("/users/:id/profile", &profileResource{})
+
+	w := doRequest(engine, "GET", "/api/users/7/profile", "")
+	doc := decodeHAL(t, w.Body.Bytes())
+	if halHref(doc, "self") != "/api/users/7/profile" {
+		t.Errorf("expected singleton self link, got %v", doc["_links"])
+	}
+	if halHref(doc, "patch") != "/api/users/7/profile" {
+		t.Errorf("expected patch link, got %v", doc["_links"])
+	}
+
+	w = doRequest(engine, "GET", "/api/users/1", "")
+	doc = decodeHAL(t, w.Body.Bytes())
+	if halHref(doc, "profile") != "/api/users/1/profile" {
+		t.Errorf("expected profile link on parent item, got %v", doc["_links"])
+	}
+}
@@ -0,0 +1,237 @@
+package restful
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NDJSONMediaType is the media type of newline-delimited JSON.
+const NDJSONMediaType = "application/x-ndjson"
+
+// streamFlushInterval bounds how long written items wait before being
+// flushed to the client.
+const streamFlushInterval = 50 * time.Millisecond
+
+// Stream is a sequence of items written to the response one by one as they
+// are produced, built with StreamOf or StreamChan. A handler returning a
+// Stream as its result responds with the items as a JSON array, or as
+// newline-delimited JSON when the request accepts NDJSONMediaType, and the
+// status it returns.
+type Stream iter.Seq2[any, error]
+
+// StreamOf returns a Stream of the items of seq. Iteration stops at the
+// first error.
+func StreamOf[T any](seq iter.Seq2[T, error]) Stream {
+	return func(yield func(any, error) bool) {
+		for item, err := range seq {
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+}
+
+// StreamChan returns a Stream of the items received from items until it is
+// closed, followed by the error received from errc if errc is not nil. The
+// producer should stop sending when the request's context is done, as the
+// stream stops receiving when the client goes away.
+func StreamChan[T any](items <-chan T, errc <-chan error) Stream {
+	return func(yield func(any, error) bool) {
+		for item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if errc == nil {
+			return
+		}
+		if err := <-errc; err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// streamer is implemented by response writers that hold responses back,
+// such as the one of routes with a timeout. startStream makes them pass
+// the response through from then on, and returns false if it must not be
+// written anymore.
+type streamer interface {
+	startStream() bool
+}
+
+// streamWriter writes the items of a Stream, flushing them periodically.
+type streamWriter struct {
+	c       *gin.Context
+	status  int
+	ndjson  bool
+	started bool
+	count   int
+
+	mu      sync.Mutex // guards writes against flushes by timer
+	timer   *time.Timer
+	pending bool
+	closed  bool
+}
+
+// writeStream responds with the items of stream. An error from the stream,
+// the end of the request's context or a failure to encode an item before
+// the response has started is rendered through errHandler like any handler
+// error. Once it has started, the response ends instead: with a final
+// {"error": ...} line holding the rendered error in NDJSON, and without its
+// closing bracket in a JSON array so that clients do not mistake it for a
+// complete one.
+func writeStream(c *gin.Context, stream Stream, status int, errHandler ErrorHandlerFunc) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w := &streamWriter{
+		c:      c,
+		status: status,
+		ndjson: c.NegotiateFormat(gin.MIMEJSON, NDJSONMediaType) == NDJSONMediaType,
+	}
+	defer w.close()
+
+	ctx := c.Request.Context()
+	for item, err := range stream {
+		if err == nil && ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		if err == nil {
+			err = w.write(item)
+		}
+		if err != nil {
+			w.fail(err, errHandler)
+			return
+		}
+	}
+	if err := w.end(); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// start sends the status and headers of the response.
+func (w *streamWriter) start() error {
+	if w.ndjson {
+		w.c.Header("Content-Type", NDJSONMediaType)
+	} else {
+		w.c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	w.c.Status(w.status)
+	if s, ok := w.c.Writer.(streamer); ok && !s.startStream() {
+		return http.ErrHandlerTimeout
+	}
+	w.started = true
+	w.timer = time.AfterFunc(streamFlushInterval, w.flush)
+	w.timer.Stop()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.c.Writer.WriteHeaderNow()
+	if !w.ndjson {
+		if _, err := w.c.Writer.WriteString("["); err != nil {
+			return err
+		}
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+func (w *streamWriter) write(item any) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case w.ndjson:
+		b = append(b, '\n')
+	case w.count > 0:
+		b = append([]byte{','}, b...)
+	}
+	if _, err := w.c.Writer.Write(b); err != nil {
+		return err
+	}
+	w.count++
+	if !w.pending {
+		w.pending = true
+		w.timer.Reset(streamFlushInterval)
+	}
+	return nil
+}
+
+// end completes the response.
+func (w *streamWriter) end() error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	if w.ndjson {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.c.Writer.WriteString("]")
+	return err
+}
+
+// fail ends the response with err.
+func (w *streamWriter) fail(err error, errHandler ErrorHandlerFunc) {
+	if !w.started {
+		dropCachePolicy(w.c)
+		renderError(w.c, err, 0, errHandler)
+		return
+	}
+
+	// The status has been sent: the error is rendered through a copy of
+	// the context, for observers, and only its body is kept.
+	out := w.c.Copy()
+	rendered := newBufferedWriter(w.c.Writer)
+	out.Writer = rendered
+	renderError(out, err, 0, errHandler)
+	if v, ok := out.Get(responseErrorKey); ok {
+		w.c.Set(responseErrorKey, v)
+	}
+	if !w.ndjson || rendered.body.Len() == 0 {
+		return
+	}
+	line := append([]byte(`{"error":`), rendered.body.Bytes()...)
+	line = append(line, "}\n"...)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = w.c.Writer.Write(line)
+}
+
+// flush sends the items written since the last flush.
+func (w *streamWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.c.Writer.Flush()
+	}
+	w.pending = false
+}
+
+func (w *streamWriter) close() {
+	if !w.started {
+		return
+	}
+	w.timer.Stop()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.c.Writer.Flush()
+}
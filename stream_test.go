@@ -0,0 +1,315 @@
+package restful
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type exportRecord struct {
+	ID    int    `json:"id"`
+	Owner string `json:"owner"`
+}
+
+// recordStream streams n records, failing with err after them if set, and
+// counts the records produced in produced if it is not nil.
+func recordStream(n int, err error, produced *int) iter.Seq2[exportRecord, error] {
+	return func(yield func(exportRecord, error) bool) {
+		for i := 1; i <= n; i++ {
+			if produced != nil {
+				*produced = i
+			}
+			if !yield(exportRecord{ID: i, Owner: []string{"alice", "bob"}[i%2]}, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(exportRecord{}, err)
+		}
+	}
+}
+
+// recordExportResource streams its records; the n and fail query
+// parameters set how many and whether the stream fails after them.
+type recordExportResource struct{}
+
+func (r *recordExportResource) StreamList(c *gin.Context) (Stream, error) {
+	if c.Query("reject") != "" {
+		return nil, Abort(http.StatusBadRequest, "bad export", WithCode("BAD_EXPORT"))
+	}
+	n := 3
+	if c.Query("n") == "0" {
+		n = 0
+	}
+	var err error
+	if c.Query("fail") != "" {
+		err = Abort(http.StatusBadGateway, "upstream failed", WithCode("UPSTREAM"))
+	}
+	return StreamOf(recordStream(n, err, nil)), nil
+}
+
+// List is shadowed by StreamList.
+func (r *recordExportResource) List(c *gin.Context) (any, int, error) {
+	return []string{"not streamed"}, http.StatusOK, nil
+}
+
+// ownedExportResource only shows records to their owner.
+type ownedExportResource struct {
+	recordExportResource
+}
+
+func (r *ownedExportResource) FilterRow(p *Principal, item any, c *gin.Context) bool {
+	return p != nil && item.(exportRecord).Owner == p.Subject
+}
+
+// funcExportResource streams what its function returns.
+type funcExportResource func(c *gin.Context) Stream
+
+func (r funcExportResource) StreamList(c *gin.Context) (Stream, error) {
+	return r(c), nil
+}
+
+// --- helpers ---
+
+func setupStreamRouter(resource any, opts ...ResourceOption) *gin.Engine {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/exports", resource, opts...)
+	return engine
+}
+
+func doNDJSONRequest(engine *gin.Engine, path string) *httptest.ResponseRecorder {
+	return doRequestWithHeaders(engine, "GET", path, map[string]string{"Accept": NDJSONMediaType})
+}
+
+// --- tests ---
+
+func TestStream_JSONArray(t *testing.T) {
+	engine := setupStreamRouter(&recordExportResource{})
+
+	w := doRequest(engine, "GET", "/api/exports", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("expected a JSON response, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var records []exportRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil || len(records) != 3 || records[2].ID != 3 {
+		t.Errorf("expected 3 records, got %v (%s)", err, w.Body.String())
+	}
+
+	w = doRequest(engine, "GET", "/api/exports?n=0", "")
+	if w.Body.String() != "[]" {
+		t.Errorf("expected an empty array, got %s", w.Body.String())
+	}
+}
+
+func TestStream_NDJSON(t *testing.T) {
+	engine := setupStreamRouter(&recordExportResource{})
+
+	w := doNDJSONRequest(engine, "/api/exports")
+	want := `{"id":1,"owner":"bob"}` + "\n" + `{"id":2,"owner":"alice"}` + "\n" + `{"id":3,"owner":"bob"}` + "\n"
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != NDJSONMediaType || w.Body.String() != want {
+		t.Errorf("expected NDJSON, got %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	if w = doNDJSONRequest(engine, "/api/exports?n=0"); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("expected an empty body, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStream_ErrorsBeforeStart(t *testing.T) {
+	engine := setupStreamRouter(&recordExportResource{})
+
+	w := doRequest(engine, "GET", "/api/exports?reject=1", "")
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"message":"bad export","code":"BAD_EXPORT"}` {
+		t.Errorf("expected the StreamList error, got %d %s", w.Code, w.Body.String())
+	}
+	w = doNDJSONRequest(engine, "/api/exports?n=0&fail=1")
+	if w.Code != http.StatusBadGateway || w.Body.String() != `{"message":"upstream failed","code":"UPSTREAM"}` {
+		t.Errorf("expected the stream error, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStream_ErrorsBeforeStartDropCachePolicy(t *testing.T) {
+	engine := setupStreamRouter(&recordExportResource{}, WithCachePolicy(CachePolicy{
+		List: &CacheControl{Public: true, MaxAge: time.Minute, Vary: []string{"Accept"}},
+	}))
+
+	w := doNDJSONRequest(engine, "/api/exports?n=0&fail=1")
+	if w.Code != http.StatusBadGateway || w.Header().Get("Cache-Control") != "" || w.Header().Get("Vary") != "" {
+		t.Errorf("expected the stream error without the cache policy, got %d %v", w.Code, w.Header())
+	}
+	w = doNDJSONRequest(engine, "/api/exports")
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "public, max-age=60" || w.Header().Get("Vary") != "Accept" {
+		t.Errorf("expected the cache policy on the stream, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestStream_ErrorsMidStream(t *testing.T) {
+	var events []ErrorEvent
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithErrorObserver(func(c *gin.Context, e ErrorEvent) {
+		events = append(events, e)
+	}))
+	api.AddResource("/exports", &recordExportResource{})
+
+	w := doNDJSONRequest(engine, "/api/exports?fail=1")
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if w.Code != http.StatusOK || len(lines) != 4 || lines[3] != `{"error":{"message":"upstream failed","code":"UPSTREAM"}}` {
+		t.Errorf("expected a final error line, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = doRequest(engine, "GET", "/api/exports?fail=1", "")
+	if !strings.HasPrefix(w.Body.String(), `[{"id":1`) || strings.HasSuffix(w.Body.String(), "]") || json.Valid(w.Body.Bytes()) {
+		t.Errorf("expected an unterminated array, got %s", w.Body.String())
+	}
+
+	if len(events) != 2 || events[0].Status != http.StatusBadGateway || events[0].Operation.Name != "List" {
+		t.Errorf("expected the errors to be observed, got %+v", events)
+	}
+}
+
+func TestStream_StopsWhenClientGoesAway(t *testing.T) {
+	produced := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	engine := setupStreamRouter(funcExportResource(func(c *gin.Context) Stream {
+		return StreamOf(func(yield func(exportRecord, error) bool) {
+			for i := 1; i <= 100; i++ {
+				produced = i
+				if i == 3 {
+					cancel()
+				}
+				if !yield(exportRecord{ID: i}, nil) {
+					return
+				}
+			}
+		})
+	}))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/exports", nil).WithContext(ctx))
+	if produced != 3 {
+		t.Errorf("expected the producer to stop after 3 records, got %d", produced)
+	}
+	if w.Body.String() != `[{"id":1,"owner":""},{"id":2,"owner":""}` {
+		t.Errorf("expected the records sent so far, got %s", w.Body.String())
+	}
+}
+
+func TestStream_Chan(t *testing.T) {
+	engine := setupStreamRouter(funcExportResource(func(c *gin.Context) Stream {
+		items, errc := make(chan exportRecord), make(chan error, 1)
+		go func() {
+			defer close(items)
+			for i := 1; i <= 2; i++ {
+				select {
+				case items <- exportRecord{ID: i}:
+				case <-c.Request.Context().Done():
+					return
+				}
+			}
+			errc <- errors.New("disk failure")
+		}()
+		return StreamChan(items, errc)
+	}))
+
+	w := doNDJSONRequest(engine, "/api/exports")
+	want := `{"id":1,"owner":""}` + "\n" + `{"id":2,"owner":""}` + "\n" + `{"error":{"message":"internal server error"}}` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("expected the records and the error, got %q", w.Body.String())
+	}
+}
+
+func TestStream_Flushes(t *testing.T) {
+	next := make(chan struct{})
+	engine := setupStreamRouter(funcExportResource(func(c *gin.Context) Stream {
+		return StreamOf(func(yield func(exportRecord, error) bool) {
+			for i := 1; i <= 2; i++ {
+				if !yield(exportRecord{ID: i}, nil) {
+					return
+				}
+				<-next
+			}
+		})
+	}))
+	server := httptest.NewServer(engine)
+	defer server.Close()
+	defer close(next)
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/exports", nil)
+	req.Header.Set("Accept", NDJSONMediaType)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// The first record arrives while the producer waits for the second.
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != `{"id":1,"owner":""}`+"\n" {
+		t.Errorf("expected the first record, got %q (%v)", line, err)
+	}
+}
+
+func TestStream_RowFilter(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.Use(func(c *gin.Context) {
+		SetPrincipal(c, &Principal{Subject: "bob"})
+	})
+	api.AddResource("/exports", &ownedExportResource{})
+
+	w := doRequest(engine, "GET", "/api/exports", "")
+	if w.Body.String() != `[{"id":1,"owner":"bob"},{"id":3,"owner":"bob"}]` {
+		t.Errorf("expected bob's records only, got %s", w.Body.String())
+	}
+}
+
+func TestStream_Timeout(t *testing.T) {
+	engine := setupStreamRouter(funcExportResource(func(c *gin.Context) Stream {
+		return StreamOf(func(yield func(exportRecord, error) bool) {
+			if c.Query("slow") == "" && !yield(exportRecord{ID: 1}, nil) {
+				return
+			}
+			<-c.Request.Context().Done()
+			yield(exportRecord{ID: 2}, nil)
+		})
+	}), WithTimeout(20*time.Millisecond))
+
+	// Before the first record, the timeout gets the usual response.
+	w := doNDJSONRequest(engine, "/api/exports?slow=1")
+	if w.Code != http.StatusGatewayTimeout || w.Body.String() != `{"message":"request timed out","code":"TIMEOUT"}` {
+		t.Errorf("expected a timeout response, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Afterwards, it ends the stream.
+	w = doNDJSONRequest(engine, "/api/exports")
+	want := `{"id":1,"owner":""}` + "\n" + `{"error":{"message":"request timed out","code":"TIMEOUT"}}` + "\n"
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("expected the stream to end with the timeout, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStream_NotCached(t *testing.T) {
+	produced := 0
+	engine := setupStreamRouter(funcExportResource(func(c *gin.Context) Stream {
+		return StreamOf(recordStream(2, nil, &produced))
+	}), WithResponseCache())
+
+	doRequest(engine, "GET", "/api/exports", "")
+	produced = 0
+	w := doRequest(engine, "GET", "/api/exports", "")
+	if produced != 2 || w.Header().Get("Age") != "" {
+		t.Errorf("expected streams not to be cached, got %d records produced", produced)
+	}
+}
@@ -0,0 +1,331 @@
+package restful
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutOption configures WithTimeout and WithGlobalTimeout.
+type TimeoutOption func(*timeoutConfig)
+
+type timeoutConfig struct {
+	timeout    time.Duration
+	operations map[string]time.Duration
+	status     int
+}
+
+// WithOperationTimeout gives the named operation ("List", "Post", an action
+// name, ...) its own timeout. Zero disables the timeout for the operation.
+func WithOperationTimeout(operation string, timeout time.Duration) TimeoutOption {
+	return func(cfg *timeoutConfig) {
+		if cfg.operations == nil {
+			cfg.operations = make(map[string]time.Duration)
+		}
+		cfg.operations[operation] = timeout
+	}
+}
+
+// WithTimeoutStatus sets the status of timeout responses, e.g.
+// http.StatusServiceUnavailable. The default is http.StatusGatewayTimeout.
+func WithTimeoutStatus(status int) TimeoutOption {
+	return func(cfg *timeoutConfig) {
+		cfg.status = status
+	}
+}
+
+func newTimeoutConfig(timeout time.Duration, opts []TimeoutOption) *timeoutConfig {
+	cfg := &timeoutConfig{timeout: timeout, status: http.StatusGatewayTimeout}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	for _, d := range cfg.operations {
+		if d < 0 {
+			panic(fmt.Sprintf("gin-restful: invalid timeout %s", d))
+		}
+	}
+	if timeout < 0 {
+		panic(fmt.Sprintf("gin-restful: invalid timeout %s", timeout))
+	}
+	return cfg
+}
+
+// WithTimeout bounds the time the resource's handlers may take. Each
+// handler runs with a context whose deadline is timeout away, available as
+// c.Request.Context(), which is also canceled when the client goes away.
+// When the deadline passes first, the request is answered with a 504
+// Gateway Timeout HTTPError with the code "TIMEOUT" through the API's error
+// handler, and whatever the handler returns or writes afterwards is
+// discarded; a canceled request gets the error the context.Canceled
+// mapping gives, 499 by default. The error handler sees the request, keys
+// and params as they were when the handler started. Handlers should stop work when the context
+// is done: the response is sent at the deadline, but the request does not
+// complete until the handler returns. Responses of routes with a timeout
+// are buffered until the handler returns, except streamed ones, which end
+// with the timeout error once started; see Stream. It overrides
+// WithGlobalTimeout.
+func WithTimeout(timeout time.Duration, opts ...TimeoutOption) ResourceOption {
+	cfg := newTimeoutConfig(timeout, opts)
+	return func(rc *resourceConfig) {
+		rc.timeout = cfg
+	}
+}
+
+// WithGlobalTimeout sets a timeout for the handlers of every resource of
+// the API that does not set its own with WithTimeout. It takes the same
+// options as WithTimeout.
+func WithGlobalTimeout(timeout time.Duration, opts ...TimeoutOption) APIOption {
+	cfg := newTimeoutConfig(timeout, opts)
+	return func(api *API) {
+		api.timeout = cfg
+	}
+}
+
+// duration returns the timeout of the named operation, zero for none.
+func (cfg *timeoutConfig) duration(name string) time.Duration {
+	if d, ok := cfg.operations[name]; ok {
+		return d
+	}
+	return cfg.timeout
+}
+
+// withTimeout runs h in its own goroutine with a deadline of d, responding
+// with a timeout error through errHandler if h has not returned by then.
+func (cfg *timeoutConfig) withTimeout(d time.Duration, errHandler ErrorHandlerFunc, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeoutCause(c.Request.Context(), d, Abort(cfg.status, "request timed out", WithCode("TIMEOUT")))
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		// Once h starts, c belongs to its goroutine: a timeout error is
+		// rendered from a copy of the request, keys and params taken now.
+		out := c.Copy()
+		out.Request = c.Request.Clone(ctx)
+
+		dst := c.Writer
+		buffered := newBufferedWriter(dst)
+		c.Writer = buffered
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			defer func() {
+				panicked = recover()
+			}()
+			h(c)
+		}()
+
+		finished := false
+		select {
+		case <-done:
+			finished = true
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() == nil {
+			c.Writer = dst
+			if panicked != nil {
+				panic(panicked)
+			}
+			buffered.flushTo(dst)
+			return
+		}
+
+		// A streamed response has already started: the stream ends with
+		// the timeout error itself.
+		if !buffered.discard() {
+			<-done
+			c.Writer = dst
+			if panicked != nil {
+				panic(panicked)
+			}
+			return
+		}
+
+		// The handler must not write to the response from now on, and c
+		// is still in use by it: the error is rendered through the copy.
+		err := context.Cause(ctx)
+		rendered := newBufferedWriter(dst)
+		out.Writer = rendered
+		renderError(out, err, cfg.status, errHandler)
+		rendered.header.Set("Content-Length", strconv.Itoa(rendered.body.Len()))
+		rendered.flushTo(dst)
+		dst.Flush()
+
+		// gin reuses c once the request completes, so wait for the handler
+		// to let go of it.
+		if !finished {
+			<-done
+		}
+		c.Writer = dst
+		if v, ok := out.Get(responseErrorKey); ok {
+			c.Set(responseErrorKey, v)
+		}
+		c.Abort()
+		if panicked != nil {
+			panic(panicked)
+		}
+	}
+}
+
+// bufferedWriter holds a response until it is flushed to the writer it was
+// created for, and drops writes once discarded. Streamed responses are
+// passed through once started.
+type bufferedWriter struct {
+	gin.ResponseWriter
+
+	mu        sync.Mutex
+	header    http.Header
+	body      bytes.Buffer
+	status    int
+	wrote     bool
+	discarded bool
+	streaming bool
+}
+
+func newBufferedWriter(w gin.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{ResponseWriter: w, header: w.Header().Clone(), status: http.StatusOK}
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(status)
+	} else if status > 0 && !w.wrote {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.wrote = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.discarded {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wrote = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *bufferedWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return w.ResponseWriter.Size()
+	}
+	if !w.wrote {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wrote || w.streaming
+}
+
+// Flush is a no-op until a streamed response starts: the response is sent
+// as a whole once the handler returns.
+func (w *bufferedWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// discard drops the response, unless a streamed response has started, in
+// which case it returns false.
+func (w *bufferedWriter) discard() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return false
+	}
+	w.discarded = true
+	return true
+}
+
+// startStream implements streamer: it sends what was buffered and passes
+// later writes through.
+func (w *bufferedWriter) startStream() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discarded {
+		return false
+	}
+	if s, ok := w.ResponseWriter.(streamer); ok && !s.startStream() {
+		return false
+	}
+	w.flushLocked(w.ResponseWriter)
+	w.streaming = true
+	return true
+}
+
+// flushTo sends the buffered response to dst, unless it was streamed.
+func (w *bufferedWriter) flushTo(dst gin.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.streaming {
+		w.flushLocked(dst)
+	}
+}
+
+func (w *bufferedWriter) flushLocked(dst gin.ResponseWriter) {
+	h := dst.Header()
+	for name := range h {
+		if _, ok := w.header[name]; !ok {
+			delete(h, name)
+		}
+	}
+	maps.Copy(h, w.header)
+	dst.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		_, _ = dst.Write(w.body.Bytes())
+	} else if w.wrote {
+		dst.WriteHeaderNow()
+	}
+}
@@ -0,0 +1,183 @@
+package restful
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- test resources ---
+
+type slowResource struct {
+	release chan struct{}
+	late    chan struct{}
+}
+
+// Get ignores its context and blocks until released.
+func (r *slowResource) Get(id string, c *gin.Context) (any, int, error) {
+	<-r.release
+	c.Header("X-Late", "1")
+	close(r.late)
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+// List honors its context.
+func (r *slowResource) List(c *gin.Context) (any, int, error) {
+	if _, ok := c.Request.Context().Deadline(); !ok {
+		return nil, 0, Abort(http.StatusInternalServerError, "no deadline")
+	}
+	if c.Query("fast") != "" {
+		c.Header("Location", "/api/things/1")
+		return []string{"a"}, http.StatusOK, nil
+	}
+	<-c.Request.Context().Done()
+	return nil, 0, c.Request.Context().Err()
+}
+
+func newSlowResource() *slowResource {
+	return &slowResource{release: make(chan struct{}), late: make(chan struct{})}
+}
+
+// --- tests ---
+
+func TestTimeout_RespondsAtDeadline(t *testing.T) {
+	resource := newSlowResource()
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/things", resource, WithTimeout(20*time.Millisecond))
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/things/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout || string(body) != `{"message":"request timed out","code":"TIMEOUT"}` {
+		t.Errorf("expected a timeout response while the handler runs, got %d %s", resp.StatusCode, body)
+	}
+	if resp.Header.Get("X-Late") != "" {
+		t.Error("expected no header from the handler")
+	}
+
+	close(resource.release)
+	<-resource.late
+}
+
+func TestTimeout_LateResultIsDiscarded(t *testing.T) {
+	resource := newSlowResource()
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/things", resource, WithTimeout(10*time.Millisecond))
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		close(resource.release)
+	}()
+	w := doRequest(engine, "GET", "/api/things/1", "")
+	if w.Code != http.StatusGatewayTimeout || w.Header().Get("X-Late") != "" || w.Body.String() != `{"message":"request timed out","code":"TIMEOUT"}` {
+		t.Errorf("expected only the timeout response, got %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestTimeout_CooperativeHandler(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/things", newSlowResource(), WithTimeout(10*time.Millisecond, WithTimeoutStatus(http.StatusServiceUnavailable)))
+
+	w := doRequest(engine, "GET", "/api/things", "")
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != `{"message":"request timed out","code":"TIMEOUT"}` {
+		t.Errorf("expected 503, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(engine, "GET", "/api/things?fast=1", "")
+	if w.Code != http.StatusOK || w.Body.String() != `["a"]` || w.Header().Get("Location") != "/api/things/1" {
+		t.Errorf("expected the response of a handler within its deadline, got %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestTimeout_GlobalAndOperationOverrides(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithGlobalTimeout(10*time.Millisecond))
+	api.AddResource("/global", newSlowResource())
+	api.AddResource("/exempt", &fullCRUDResource{}, WithTimeout(10*time.Millisecond, WithOperationTimeout("List", 0)))
+
+	if w := doRequest(engine, "GET", "/api/global", ""); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected the global timeout to apply, got %d", w.Code)
+	}
+	if w := doRequest(engine, "GET", "/api/exempt", ""); w.Code != http.StatusOK {
+		t.Errorf("expected List to be exempt, got %d", w.Code)
+	}
+}
+
+func TestTimeout_ClientGone(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/things", newSlowResource(), WithTimeout(time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequestWithContext(ctx, "GET", "/api/things", nil)
+	w := httptest.NewRecorder()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	engine.ServeHTTP(w, req)
+	if w.Code != StatusClientClosedRequest {
+		t.Errorf("expected 499, got %d", w.Code)
+	}
+}
+
+func TestTimeout_ObserversSeeTimeout(t *testing.T) {
+	var got CallResult
+	engine := gin.New()
+	api := NewAPI(engine, "/api", WithObserver(ObserverFunc(func(c *gin.Context, op Operation) func(CallResult) {
+		return func(result CallResult) { got = result }
+	})))
+	api.AddResource("/things", newSlowResource(), WithTimeout(10*time.Millisecond))
+
+	doRequest(engine, "GET", "/api/things", "")
+	if got.Status != http.StatusGatewayTimeout || got.ErrorCode != "TIMEOUT" {
+		t.Errorf("expected the timeout to be observed, got %+v", got)
+	}
+}
+
+func TestTimeout_InvalidPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a negative timeout")
+		}
+	}()
+	WithTimeout(-time.Second)
+}
+
+// busyResource keeps reassigning c.Request and setting keys past its
+// deadline.
+type busyResource struct{}
+
+func (busyResource) Get(id string, c *gin.Context) (any, int, error) {
+	ctx := c.Request.Context()
+	for i := 0; ctx.Err() == nil; i++ {
+		c.Request = c.Request.WithContext(ctx)
+		c.Set("iteration", i)
+	}
+	for i := range 1000 {
+		c.Request = c.Request.WithContext(ctx)
+		c.Set("iteration", i)
+	}
+	return nil, 0, ctx.Err()
+}
+
+func TestTimeout_RendersFromSnapshot(t *testing.T) {
+	engine := gin.New()
+	api := NewAPI(engine, "/api")
+	api.AddResource("/things", busyResource{}, WithTimeout(10*time.Millisecond))
+
+	w := doRequest(engine, "GET", "/api/things/1", "")
+	if w.Code != http.StatusGatewayTimeout || w.Body.String() != `{"message":"request timed out","code":"TIMEOUT"}` {
+		t.Errorf("expected a timeout response, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+// Package tracing traces the resource calls of a gin-restful API with
+// OpenTelemetry.
+//
+//	api := restful.NewAPI(engine, "/api",
+//		restful.WithObserver(tracing.New()),
+//		restful.WithCorrelationID(tracing.TraceID),
+//	)
+//
+// Each call gets a server span named after its resource and operation, such
+// as "users.Get", carrying the route template, id, status and error code.
+// The span continues the trace of the W3C traceparent header of the request
+// and is set on the request context, so spans started by handlers from
+// c.Request.Context() are its children.
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer.
+const ScopeName = "github.com/hwangseonu/gin-restful/tracing"
+
+// Attribute keys set on spans in addition to the HTTP semantic conventions.
+const (
+	ResourceKey  = attribute.Key("restful.resource")
+	OperationKey = attribute.Key("restful.operation")
+	IDKey        = attribute.Key("restful.id")
+	ErrorCodeKey = attribute.Key("restful.error_code")
+)
+
+// Option configures a Tracer.
+type Option func(*Tracer)
+
+// WithTracerProvider sets the provider spans are created with. The default
+// is the global provider from otel.GetTracerProvider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.provider = provider
+	}
+}
+
+// WithPropagator sets how the trace context is read from request headers.
+// The default is W3C Trace Context.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(t *Tracer) {
+		t.propagator = propagator
+	}
+}
+
+// Tracer is a restful.Observer creating a span for every resource call.
+type Tracer struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+}
+
+// New returns a Tracer.
+func New(opts ...Option) *Tracer {
+	t := &Tracer{propagator: propagation.TraceContext{}}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.provider == nil {
+		t.provider = otel.GetTracerProvider()
+	}
+	t.tracer = t.provider.Tracer(ScopeName)
+	return t
+}
+
+// StartCall implements restful.Observer.
+func (t *Tracer) StartCall(c *gin.Context, op restful.Operation) func(restful.CallResult) {
+	ctx := t.propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", op.Method),
+		attribute.String("http.route", op.Route),
+		ResourceKey.String(op.Resource),
+		OperationKey.String(op.Name),
+	}
+	if op.ID != "" {
+		attrs = append(attrs, IDKey.String(op.ID))
+	}
+	ctx, span := t.tracer.Start(ctx, spanName(op),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+	c.Request = c.Request.WithContext(ctx)
+
+	return func(result restful.CallResult) {
+		if result.Operation.Name != op.Name {
+			span.SetName(spanName(result.Operation))
+			span.SetAttributes(OperationKey.String(result.Operation.Name))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", result.Status))
+		if result.ErrorCode != "" {
+			span.SetAttributes(ErrorCodeKey.String(result.ErrorCode))
+		}
+		if result.Err != nil {
+			span.RecordError(result.Err)
+		}
+		// As for HTTP server spans, only 5xx responses are failures of the
+		// server; 4xx responses leave the status unset.
+		if result.Status >= 500 {
+			msg := ""
+			if result.Err != nil {
+				msg = result.Err.Error()
+			}
+			span.SetStatus(codes.Error, msg)
+		}
+		span.End()
+	}
+}
+
+func spanName(op restful.Operation) string {
+	return op.Resource + "." + op.Name
+}
+
+// TraceID returns the trace ID of the span of the request, or "" when it is
+// not traced. It can be passed to restful.WithCorrelationID so that error
+// responses point at their trace.
+func TraceID(c *gin.Context) string {
+	sc := trace.SpanContextFromContext(c.Request.Context())
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hwangseonu/gin-restful"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// --- helpers ---
+
+type userResource struct{}
+
+func (r *userResource) Get(id string, c *gin.Context) (any, int, error) {
+	_, span := trace.SpanFromContext(c.Request.Context()).TracerProvider().Tracer("test").Start(c.Request.Context(), "load")
+	defer span.End()
+
+	switch id {
+	case "missing":
+		return nil, 0, restful.Abort(http.StatusNotFound, "not found", restful.WithCode("USER_NOT_FOUND"))
+	case "broken":
+		return nil, 0, http.ErrHandlerTimeout
+	}
+	return gin.H{"id": id}, http.StatusOK, nil
+}
+
+func setupTracingRouter(t *testing.T, opts ...restful.APIOption) (*gin.Engine, *tracetest.InMemoryExporter) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+
+	engine := gin.New()
+	opts = append(opts, restful.WithObserver(New(WithTracerProvider(provider))))
+	api := restful.NewAPI(engine, "/api", opts...)
+	api.AddResource("/users", &userResource{})
+	return engine, exporter
+}
+
+func get(engine *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func attrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+// serverSpan returns the single span named name.
+func serverSpan(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %q in %v", name, exporter.GetSpans())
+	return tracetest.SpanStub{}
+}
+
+// --- tests ---
+
+func TestTracer(t *testing.T) {
+	engine, exporter := setupTracingRouter(t)
+	get(engine, "/api/users/7", nil)
+
+	span := serverSpan(t, exporter, "users.Get")
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("expected a server span, got %v", span.SpanKind)
+	}
+	a := attrs(span)
+	if a["http.route"].AsString() != "/api/users/:id" || a[IDKey].AsString() != "7" ||
+		a[ResourceKey].AsString() != "users" || a[OperationKey].AsString() != "Get" ||
+		a["http.response.status_code"].AsInt64() != 200 {
+		t.Errorf("unexpected attributes %v", span.Attributes)
+	}
+	if span.Status.Code != codes.Unset {
+		t.Errorf("expected an unset status, got %v", span.Status)
+	}
+
+	child := serverSpan(t, exporter, "load")
+	if child.Parent.SpanID() != span.SpanContext.SpanID() {
+		t.Error("expected spans started by the handler to be children of the call span")
+	}
+}
+
+func TestTracer_Errors(t *testing.T) {
+	engine, exporter := setupTracingRouter(t)
+	get(engine, "/api/users/missing", nil)
+	get(engine, "/api/users/broken", nil)
+
+	spans := exporter.GetSpans()
+	var notFound, broken tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name != "users.Get" {
+			continue
+		}
+		if attrs(span)[IDKey].AsString() == "missing" {
+			notFound = span
+		} else {
+			broken = span
+		}
+	}
+
+	if attrs(notFound)[ErrorCodeKey].AsString() != "USER_NOT_FOUND" || notFound.Status.Code != codes.Unset {
+		t.Errorf("expected the error code and an unset status for a 404, got %v %v", notFound.Attributes, notFound.Status)
+	}
+	if len(notFound.Events) != 1 || notFound.Events[0].Name != "exception" {
+		t.Errorf("expected the error to be recorded, got %v", notFound.Events)
+	}
+	if broken.Status.Code != codes.Error || !strings.Contains(broken.Status.Description, "timeout") {
+		t.Errorf("expected an error status for a 500, got %v", broken.Status)
+	}
+}
+
+func TestTracer_PropagatesTraceContext(t *testing.T) {
+	engine, exporter := setupTracingRouter(t, restful.WithCorrelationID(TraceID))
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	w := get(engine, "/api/users/missing", map[string]string{
+		"traceparent": "00-" + traceID + "-00f067aa0ba902b7-01",
+	})
+
+	span := serverSpan(t, exporter, "users.Get")
+	if span.SpanContext.TraceID().String() != traceID || span.Parent.SpanID().String() != "00f067aa0ba902b7" || !span.Parent.IsRemote() {
+		t.Errorf("expected the span to continue the incoming trace, got %v parent %v", span.SpanContext, span.Parent)
+	}
+	if !strings.Contains(w.Body.String(), `"correlation_id":"`+traceID+`"`) {
+		t.Errorf("expected the trace ID as correlation ID, got %s", w.Body.String())
+	}
+}